@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	c "github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/importer"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/validator"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage/postgres"
+)
+
+var (
+	configPath string
+	filePath   string
+	format     string
+	dryRun     bool
+	onConflict string
+	editor     string
+)
+
+func init() {
+	flag.StringVar(&configPath, "config", "", "path to config file")
+	flag.StringVar(&filePath, "file", "", "path to imported csv or ndjson file")
+	flag.StringVar(&format, "format", "", "format of the file (csv, ndjson), by default it is taken from the file extension")
+	flag.BoolVar(&dryRun, "dry-run", false, "only validate the file without saving cars")
+	flag.StringVar(&onConflict, "on-conflict", string(models.ConflictSkip), "what to do with existing cars: skip, update or fail")
+	flag.StringVar(&editor, "editor", "import-cli", "name which is saved as the editor of the imported cars")
+}
+
+// imports cars from the file directly to the database and prints report to stdout
+func main() {
+	flag.Parse()
+	if filePath == "" {
+		fmt.Fprintln(os.Stderr, "file is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	policy := models.ConflictPolicy(onConflict)
+	if !policy.Valid() {
+		fmt.Fprintln(os.Stderr, "on-conflict must be skip, update or fail")
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg, err := c.LoadConfig(configPath)
+	if err != nil {
+		panic(fmt.Sprintf("cant load config from path %s: %s", configPath, err.Error()))
+	}
+	logger := l.SetupLogger(cfg.LogLevel)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filePath), ".")
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		logger.Error("failed to open file", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer file.Close()
+	carValidator, err := validator.New(cfg.ValidatorConfig)
+	if err != nil {
+		logger.Error("failed to initialise validator", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	rows, err := importer.NewRowReader(format, file, carValidator)
+	if err != nil {
+		logger.Error("failed to read file", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	postgresRepo, err := postgres.NewPostgresProvider(ctx, cfg.PostgresConfig)
+	if err != nil {
+		logger.Error("failed to initialise postgres provider", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer postgresRepo.Close()
+	catalogService := service.NewCatalogService(logger, postgresRepo)
+	if err = catalogService.Load(ctx); err != nil {
+		logger.Error("failed to load catalog", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	// car info getter isnt used by the import
+	carService := service.NewCarService(logger, postgresRepo, nil, carValidator, catalogService, cfg.AuthConfig.Access)
+
+	ctx = auth.ContextWithPrincipal(ctx, auth.Principal{Name: editor})
+	report, err := carService.ImportCars(ctx, rows, dryRun, policy)
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	out.Encode(report)
+	if err != nil {
+		logger.Error("failed to import cars", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
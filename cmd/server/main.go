@@ -9,15 +9,21 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/confirm"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/http/helper"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/http/parser"
 	v1 "github.com/EwvwGeN/EffectiveMobile_assignment/http/v1"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
 	c "github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
 	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/metrics"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/server"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage/postgres"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/validator"
 
 	_ "github.com/EwvwGeN/EffectiveMobile_assignment/http/swagger"
 
@@ -38,6 +44,14 @@ func init() {
 //
 // @host localhost:9099
 // @BasePath /
+//
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+//
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 //go:generate swag init --parseDependency --parseInternal  -d .,../../http/v1 -o ../../http/swagger/
 func main() {
 	flag.Parse()
@@ -50,6 +64,18 @@ func main() {
 	logger.Debug("config data", slog.Any("config", cfg))
 	mainCtx, cancel := context.WithCancel(context.Background())
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingConfig)
+	if err != nil {
+		logger.Error("failed to initialise tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	carValidator, err := validator.New(cfg.ValidatorConfig)
+	if err != nil {
+		logger.Error("failed to initialise validator", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	carInfoGetter, err := helper.GetCarInfoGetter(logger, cfg.CarInfoGetterUrl, parser.ParseFromExternalApi)
 	if err != nil {
 		logger.Error("failed to initialise info getter", slog.String("error", err.Error()))
@@ -62,35 +88,269 @@ func main() {
 		os.Exit(1)
 	}
 
-	carService := service.NewCarService(logger, postgresRepo, carInfoGetter)
+	metrics.RegisterPoolStats(postgresRepo.Stat)
+
+	catalogService := service.NewCatalogService(logger, postgresRepo)
+	if err = catalogService.Load(context.Background()); err != nil {
+		logger.Error("failed to load catalog", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	carService := service.NewCarService(logger, postgresRepo, carInfoGetter, carValidator, catalogService, cfg.AuthConfig.Access)
+	suggestService := service.NewSuggestService(logger, postgresRepo, cfg.SuggestConfig, cfg.AuthConfig.Access)
 
 	hserver := server.NewHttpServer(cfg.HttpConfig, logger)
+	authenticator := server.NewAuthenticator(cfg.AuthConfig)
+	if !cfg.AuthConfig.Enabled {
+		logger.Warn("authentication is disabled, all routes are available without credentials")
+	}
+	limiter := server.NewRateLimiter(cfg.HttpConfig.RateLimit, logger)
+	// failed authentications are limited by ip, the limits of the routes are applied before the scope checks
+	hserver.Use(limiter.LimitUnauthorized("auth"))
+	// routes without credentials, the other routes are closed even if they dont require scope
+	hserver.Use(authenticator.Middleware(logger, "/healthz", "/readyz", "/metrics", "/api/swagger/"))
+
+	idempotency := server.NewIdempotency(cfg.HttpConfig.Idempotency, postgresRepo, logger)
+	go idempotency.RunCleanup(mainCtx)
+	go catalogService.RunRefresh(mainCtx, cfg.CatalogConfig.RefreshInterval)
+	go suggestService.RunRefresh(mainCtx, cfg.SuggestConfig.RefreshInterval)
+
+	timeouts := cfg.HttpConfig.Timeouts
+	cacheControl := cfg.HttpConfig.CacheControl
+	carAddHandler := limiter.Limit(
+		"add",
+		authenticator.Require(auth.ScopeCarsWrite, idempotency.Handle(timeouts.Add, server.WithTimeout(timeouts.Add, v1.CarAdd(logger, carValidator, carService)))),
+	)
+	carGetOneHandler := limiter.Limit(
+		"get_one",
+		authenticator.Require(auth.ScopeCarsRead, server.WithCacheControl(cacheControl.GetOne, server.WithTimeout(timeouts.GetOne, v1.CarGetOne(logger, carService)))),
+	)
+	carGetByVinHandler := limiter.Limit(
+		"get_one",
+		authenticator.Require(auth.ScopeCarsRead, server.WithCacheControl(cacheControl.GetOne, server.WithTimeout(timeouts.GetOne, v1.CarGetByVin(logger, carValidator, carService)))),
+	)
+	carGetAllHandler := limiter.Limit(
+		"get_all",
+		authenticator.Require(auth.ScopeCarsRead, server.WithCacheControl(cacheControl.GetAll, server.WithTimeout(timeouts.GetAll, v1.CarGetAll(logger, cfg.HttpConfig.Stats, carService, carService, postgres.AddFilter, postgres.AddSort, postgres.AddFields)))),
+	)
+	carStatsHandler := limiter.Limit(
+		"stats",
+		authenticator.Require(auth.ScopeCarsRead, server.WithTimeout(timeouts.GetAll, v1.CarStats(logger, cfg.HttpConfig.Stats, carService, postgres.AddFilter))),
+	)
+	carSuggestHandler := limiter.Limit(
+		"suggest",
+		authenticator.Require(auth.ScopeCarsRead, server.WithTimeout(timeouts.GetOne, v1.CarSuggest(logger, cfg.SuggestConfig, suggestService))),
+	)
+	carExportHandler := limiter.Limit(
+		"export",
+		authenticator.Require(auth.ScopeCarsRead, server.WithTimeout(timeouts.Export, v1.CarExport(logger, cfg.HttpConfig.Export, carService, postgres.AddFilter, postgres.AddSort, postgres.AddFields))),
+	)
+	carImportHandler := limiter.Limit(
+		"import",
+		authenticator.Require(auth.ScopeCarsWrite, server.WithTimeout(timeouts.Import, v1.CarImport(logger, cfg.HttpConfig.Import, carValidator, carService))),
+	)
+	carEditHandler := limiter.Limit(
+		"edit",
+		authenticator.Require(auth.ScopeCarsWrite, server.WithTimeout(timeouts.Edit, v1.CarEdit(logger, carValidator, carService))),
+	)
+	carReplaceHandler := limiter.Limit(
+		"replace",
+		authenticator.Require(auth.ScopeCarsWrite, server.WithTimeout(timeouts.Edit, v1.CarReplace(logger, carValidator, carService))),
+	)
+	// the signer is used only by the deletes by filter, which are disabled without the secret
+	confirmSigner, err := confirm.NewSigner(cfg.HttpConfig.Batch.ConfirmSecret, cfg.HttpConfig.Batch.ConfirmTtl)
+	if err != nil && cfg.HttpConfig.Batch.DeleteByFilter {
+		logger.Error("failed to create confirm signer", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	carBatchEditHandler := limiter.Limit(
+		"batch_edit",
+		authenticator.Require(auth.ScopeCarsWrite, server.WithTimeout(timeouts.Batch, v1.CarBatchEdit(logger, carValidator, cfg.HttpConfig.Batch, carService, postgres.AddFilter))),
+	)
+	carBatchDeleteHandler := limiter.Limit(
+		"batch_delete",
+		authenticator.Require(auth.ScopeCarsDelete, server.WithTimeout(timeouts.Batch, v1.CarBatchDelete(logger, cfg.HttpConfig.Batch, confirmSigner, carService, postgres.AddFilter))),
+	)
+	carDeleteHandler := limiter.Limit(
+		"delete",
+		authenticator.Require(auth.ScopeCarsDelete, server.WithTimeout(timeouts.Delete, v1.CarDelete(logger, carService))),
+	)
+	catalogRead := func(handler http.HandlerFunc) http.HandlerFunc {
+		return limiter.Limit("catalog", authenticator.Require(auth.ScopeCarsRead, server.WithTimeout(timeouts.GetAll, handler)))
+	}
+	catalogWrite := func(handler http.HandlerFunc) http.HandlerFunc {
+		return limiter.Limit("catalog", authenticator.Require(auth.ScopeCatalogWrite, server.WithTimeout(timeouts.Edit, handler)))
+	}
 
 	hserver.RegisterHandler(
-		"/api/cars/add",
-		v1.CarAdd(logger, carService),
+		"/api/v2/cars",
+		carAddHandler,
 		http.MethodPost,
 	)
 	hserver.RegisterHandler(
-		"/api/car/{carId}",
-		v1.CarGetOne(logger, carService),
+		"/api/v2/cars",
+		carGetAllHandler,
 		http.MethodGet,
 	)
 	hserver.RegisterHandler(
-		"/api/cars",
-		v1.CarGetAll(logger, carService, postgres.AddFilter),
+		"/api/v2/cars/import",
+		carImportHandler,
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/batch/edit",
+		carBatchEditHandler,
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/batch/delete",
+		carBatchDeleteHandler,
+		http.MethodPost,
+	)
+	// must be registered before the routes with car id
+	hserver.RegisterHandler(
+		"/api/v2/cars/export",
+		carExportHandler,
 		http.MethodGet,
 	)
 	hserver.RegisterHandler(
-		"/api/car/{carId}/edit",
-		v1.CarEdit(logger, cfg.ValidatorConfig, carService),
+		"/api/v2/cars/stats",
+		carStatsHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/suggest",
+		carSuggestHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/by-vin/{vin}",
+		carGetByVinHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/{carId}",
+		carGetOneHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/{carId}",
+		carEditHandler,
 		http.MethodPatch,
 	)
 	hserver.RegisterHandler(
+		"/api/v2/cars/{carId}",
+		carReplaceHandler,
+		http.MethodPut,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/cars/{carId}",
+		carDeleteHandler,
+		http.MethodDelete,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/marks",
+		catalogRead(v1.CatalogGet(logger, catalogService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/marks",
+		catalogWrite(v1.CatalogMarkAdd(logger, catalogService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/marks/{markId}",
+		catalogWrite(v1.CatalogMarkEdit(logger, catalogService)),
+		http.MethodPut,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/marks/{markId}",
+		catalogWrite(v1.CatalogMarkDelete(logger, catalogService)),
+		http.MethodDelete,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/marks/{markId}/models",
+		catalogWrite(v1.CatalogModelAdd(logger, catalogService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/models/{modelId}",
+		catalogWrite(v1.CatalogModelEdit(logger, catalogService)),
+		http.MethodPut,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/models/{modelId}",
+		catalogWrite(v1.CatalogModelDelete(logger, catalogService)),
+		http.MethodDelete,
+	)
+	hserver.RegisterHandler(
+		"/api/v2/catalog/report",
+		catalogRead(v1.CatalogReport(logger, catalogService)),
+		http.MethodGet,
+	)
+
+	// legacy routes
+	hserver.RegisterDeprecatedHandler(
+		"/api/cars/add",
+		"/api/v2/cars",
+		carAddHandler,
+		http.MethodPost,
+	)
+	hserver.RegisterDeprecatedHandler(
+		"/api/car/{carId}",
+		"/api/v2/cars/{carId}",
+		carGetOneHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterDeprecatedHandler(
+		"/api/cars",
+		"/api/v2/cars",
+		carGetAllHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterDeprecatedHandler(
+		"/api/cars/by-vin/{vin}",
+		"/api/v2/cars/by-vin/{vin}",
+		carGetByVinHandler,
+		http.MethodGet,
+	)
+	hserver.RegisterDeprecatedHandler(
+		"/api/car/{carId}/edit",
+		"/api/v2/cars/{carId}",
+		carEditHandler,
+		http.MethodPatch,
+	)
+	hserver.RegisterDeprecatedHandler(
 		"/api/car/{carId}/delete",
-		v1.CarDelete(logger, carService),
+		"/api/v2/cars/{carId}",
+		carDeleteHandler,
 		http.MethodDelete,
 	)
+	health := server.NewHealth(cfg.HttpConfig.Health.Timeout, logger)
+	health.AddCheck("postgres", postgresRepo.Ping)
+	if cfg.HttpConfig.Health.CheckUpstream {
+		carInfoChecker, err := helper.GetCarInfoChecker(cfg.CarInfoGetterUrl)
+		if err != nil {
+			logger.Error("failed to initialise info checker", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		health.AddCheck("car_info", carInfoChecker)
+	}
+	hserver.RegisterHandler(
+		"/healthz",
+		health.Liveness,
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/readyz",
+		health.Readiness,
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/metrics",
+		metrics.Handler().ServeHTTP,
+		http.MethodGet,
+	)
 	swagParams := []func(*httpSwagger.Config){
 		httpSwagger.URL("doc.json"),
 	}
@@ -107,10 +367,23 @@ func main() {
 	signal.Notify(stopChecker, syscall.SIGTERM, syscall.SIGINT)
 	<- stopChecker
 	logger.Info("stopping service")
+	health.ShuttingDown()
+	// readiness fails during the drain, so the new requests are sent to the other instances
+	if drain := cfg.HttpConfig.Health.Drain; drain > 0 {
+		logger.Info("draining requests", slog.Duration("drain", drain))
+		select {
+		case <-time.After(drain):
+		case <-stopChecker:
+		}
+	}
 	cancel()
 	err = <-errCh
 	if err != nil {
 		logger.Error("error while stopping http server", slog.String("error", err.Error()))
 	}
+	postgresRepo.Close()
+	if err = shutdownTracing(context.Background()); err != nil {
+		logger.Error("error while stopping tracing", slog.String("error", err.Error()))
+	}
 	logger.Info("service stoped successfully")
 }
\ No newline at end of file
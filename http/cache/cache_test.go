@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2026, 10, 19, 12, 0, 0, 500, time.UTC)
+	etag := ETag([]byte(`{"car":{}}`))
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no conditions", want: false},
+		{name: "same etag", headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "weak etag in list", headers: map[string]string{"If-None-Match": `"a", W/` + etag}, want: true},
+		{name: "other etag", headers: map[string]string{"If-None-Match": `"a"`}, want: false},
+		{name: "etag takes precedence", headers: map[string]string{
+			"If-None-Match":     `"a"`,
+			"If-Modified-Since": lastModified.Format(http.TimeFormat),
+		}, want: false},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, want: true},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v2/cars/1", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			if got := NotModified(r, etag, lastModified); got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package confirm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTtl = 5 * time.Minute
+
+var (
+	ErrRequired = errors.New("confirm token is required, get it by the dry run")
+	ErrInvalid  = errors.New("confirm token is invalid or expired, selected cars could be changed after the dry run")
+	ErrNoSecret = errors.New("confirm secret is empty")
+)
+
+// signer issues tokens which confirm that the client has seen the cars affected by the operation
+type signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSigner creates signer of the tokens. The secret is required, so the token
+// is accepted by all instances and after the restart
+func NewSigner(secret string, ttl time.Duration) (*signer, error) {
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+	if ttl <= 0 {
+		ttl = defaultTtl
+	}
+	return &signer{
+		secret: []byte(secret),
+		ttl:    ttl,
+		now:    time.Now,
+	}, nil
+}
+
+// Sign returns token of the operation of the subject on the cars with these ids
+func (s *signer) Sign(subject string, ids []int) string {
+	expires := s.now().Add(s.ttl).Unix()
+	return strconv.FormatInt(expires, 10) + "." + s.mac(expires, subject, ids)
+}
+
+// Verify checks that the token is issued for the same subject and ids and isnt expired
+func (s *signer) Verify(token, subject string, ids []int) error {
+	if token == "" {
+		return ErrRequired
+	}
+	expiresPart, mac, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalid
+	}
+	expires, err := strconv.ParseInt(expiresPart, 10, 64)
+	if err != nil || s.now().Unix() > expires {
+		return ErrInvalid
+	}
+	if !hmac.Equal([]byte(mac), []byte(s.mac(expires, subject, ids))) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (s *signer) mac(expires int64, subject string, ids []int) string {
+	h := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(h, "%d\n%s\n", expires, subject)
+	for _, id := range ids {
+		fmt.Fprintf(h, "%d,", id)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
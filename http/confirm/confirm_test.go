@@ -0,0 +1,46 @@
+package confirm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	s, err := NewSigner("secret", time.Minute)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	s.now = func() time.Time { return now }
+	token := s.Sign("admin\nmark = Lada", []int{1, 2, 3})
+	tests := []struct {
+		name    string
+		token   string
+		subject string
+		ids     []int
+		after   time.Duration
+		want    error
+	}{
+		{name: "valid", token: token, subject: "admin\nmark = Lada", ids: []int{1, 2, 3}},
+		{name: "empty", token: "", subject: "admin\nmark = Lada", ids: []int{1, 2, 3}, want: ErrRequired},
+		{name: "other ids", token: token, subject: "admin\nmark = Lada", ids: []int{1, 2}, want: ErrInvalid},
+		{name: "other subject", token: token, subject: "user\nmark = Lada", ids: []int{1, 2, 3}, want: ErrInvalid},
+		{name: "expired", token: token, subject: "admin\nmark = Lada", ids: []int{1, 2, 3}, after: 2 * time.Minute, want: ErrInvalid},
+		{name: "malformed", token: "abc", subject: "admin\nmark = Lada", ids: []int{1, 2, 3}, want: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.now = func() time.Time { return now.Add(tt.after) }
+			if err := s.Verify(tt.token, tt.subject, tt.ids); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSignerWithoutSecret(t *testing.T) {
+	if _, err := NewSigner("", time.Minute); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("NewSigner() error = %v, want %v", err, ErrNoSecret)
+	}
+}
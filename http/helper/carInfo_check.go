@@ -0,0 +1,32 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GetCarInfoChecker returns function which checks that the car info source is reachable,
+// any response except 5xx means that the source is available
+func GetCarInfoChecker(sourceUrl string) (func(context.Context) error, error) {
+	parsedUrl, err := url.Parse(sourceUrl)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, parsedUrl.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCarInfoUnavailable, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%w: status %d", ErrCarInfoUnavailable, resp.StatusCode)
+		}
+		return nil
+	}, nil
+}
@@ -7,42 +7,91 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/metrics"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type parser func (map[string]interface{}) (models.Car, error)
 
+// name of the upstream in metrics
+const upstreamName = "car_info"
+
 func GetCarInfoGetter(logger *slog.Logger, sourceUrl string, parseFunc parser) (func(context.Context, string) (models.Car, error), error) {
 	parsedUrl, err := url.Parse(sourceUrl)
 	if err != nil {
 		return nil, err
 	}
-	log := logger.With("handler", "car_info_getter")
-	return func(ctx context.Context, carRegisteNum string) (models.Car, error) {
+	handlerName := slog.String("handler", "car_info_getter")
+	baseLog := logger.With(handlerName)
+	return func(ctx context.Context, carRegisteNum string) (_ models.Car, err error) {
+		log := l.FromContext(ctx, baseLog, handlerName)
 		newUrl := *parsedUrl
 		values := newUrl.Query()
 		values.Set("regNum", carRegisteNum)
 		newUrl.RawQuery = values.Encode()
-		resp, err := http.Get(newUrl.String())
+		ctx, span := tracing.Start(ctx, "car_info GET",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(http.MethodGet),
+				semconv.URLFull(newUrl.String()),
+				semconv.ServerAddress(newUrl.Hostname()),
+			),
+		)
+		defer tracing.End(span, &err)
+		start := time.Now()
+		defer func() {
+			metrics.UpstreamRequestDuration.WithLabelValues(upstreamName).Observe(time.Since(start).Seconds())
+		}()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, newUrl.String(), nil)
 		if err != nil {
-			log.Error("filed to execute get request", slog.String("error", err.Error()))
 			return models.Car{}, err
 		}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			metrics.UpstreamResponses.WithLabelValues(upstreamName, "error").Inc()
+		} else {
+			metrics.UpstreamResponses.WithLabelValues(upstreamName, strconv.Itoa(resp.StatusCode)).Inc()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return models.Car{}, err
+			}
+			log.Error("filed to execute get request", slog.String("error", err.Error()))
+			return models.Car{}, fmt.Errorf("%w: %w", ErrCarInfoUnavailable, err)
+		}
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return models.Car{}, ErrCarInfoNotFound
+		case resp.StatusCode != http.StatusOK:
+			log.Error("got unexpected response status", slog.Int("status", resp.StatusCode))
+			return models.Car{}, fmt.Errorf("%w: status %d", ErrCarInfoUnavailable, resp.StatusCode)
+		}
 		var carMap map[string]interface{}
 		err = json.NewDecoder(resp.Body).Decode(&carMap)
 		if err != nil {
 			log.Error("failed to decode response body", slog.String("error", err.Error()))
-			return models.Car{}, err
+			return models.Car{}, fmt.Errorf("%w: %w", ErrCarInfoUnavailable, err)
 		}
-		resp.Body.Close()
 		if len(carMap) == 0 {
-			return models.Car{}, fmt.Errorf("empty car info response")
+			return models.Car{}, fmt.Errorf("%w: empty car info response", ErrCarInfoNotFound)
 		}
 		log.Debug("got car map", slog.Any("car_map", carMap))
 		car, err := parseFunc(carMap)
 		if err != nil {
-			return models.Car{}, err
+			log.Error("failed to parse car info", slog.String("error", err.Error()))
+			return models.Car{}, fmt.Errorf("%w: %w", ErrCarInfoUnavailable, err)
 		}
 		return car, nil
 	}, nil
@@ -0,0 +1,8 @@
+package helper
+
+import "errors"
+
+var (
+	ErrCarInfoNotFound    = errors.New("car info not found")
+	ErrCarInfoUnavailable = errors.New("car info source is unavailable")
+)
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
 )
@@ -16,5 +17,8 @@ func ParseFromExternalApi(carMap map[string]interface{}) (models.Car, error) {
 	if err != nil {
 		return models.Car{}, err
 	}
+	if newCar.Owner == nil {
+		return models.Car{}, fmt.Errorf("car info doesnt contain owner")
+	}
 	return newCar, nil
 }
\ No newline at end of file
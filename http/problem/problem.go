@@ -0,0 +1,187 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/confirm"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/helper"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/requestid"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+)
+
+const (
+	contentType = "application/problem+json"
+
+	// nonstandard status, used when the client closed the connection before the response
+	statusClientClosedRequest = 499
+)
+
+// machine-readable codes of the problems
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeRateLimited         = "rate_limited"
+	CodeNotAcceptable       = "not_acceptable"
+	CodeUnsupportedMedia    = "unsupported_media_type"
+	CodePayloadTooLarge     = "payload_too_large"
+	CodeValidationFailed    = "validation_failed"
+	CodeCarNotFound         = "car_not_found"
+	CodeCarExist            = "car_already_exists"
+	CodeVinExist            = "vin_already_exists"
+	CodeCatalogNotFound     = "catalog_entry_not_found"
+	CodeCatalogConflict     = "catalog_conflict"
+	CodeCarInfoNotFound     = "car_info_not_found"
+	CodeCarInfoInvalid      = "car_info_invalid"
+	CodeIdempotencyMismatch = "idempotency_key_reused"
+	CodeIdempotencyConflict = "idempotency_key_in_progress"
+	CodeConfirmRequired     = "confirm_token_required"
+	CodeConfirmInvalid      = "confirm_token_invalid"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeTimeout             = "timeout"
+	CodeCanceled            = "request_canceled"
+	CodeInternal            = "internal_error"
+)
+
+// Problem is RFC 7807 response body
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestId string `json:"requestId"`
+	// all errors of the request data, used by the validation problems
+	Errors []models.FieldError `json:"errors,omitempty"`
+	// report of the stopped import, contains the rows which are already processed
+	Report *models.ImportReport `json:"report,omitempty"`
+}
+
+// Write writes problem with the given status, code and detail
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	write(w, newProblem(w, r, status, code, detail))
+}
+
+func newProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) *Problem {
+	return &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestId: requestId(w, r),
+	}
+}
+
+func write(w http.ResponseWriter, p *Problem) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(p.Status)
+	w.Write(data)
+}
+
+// BadRequest writes problem about malformed request
+func BadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// Validation writes problem about request data that didnt pass validation
+func Validation(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, detail)
+}
+
+// ValidationErrors writes problem with all errors of the request data, the first one is used as detail
+func ValidationErrors(w http.ResponseWriter, r *http.Request, errs []models.FieldError) {
+	p := newProblem(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, "")
+	if len(errs) != 0 {
+		p.Detail = errs[0].Message
+	}
+	p.Errors = errs
+	write(w, p)
+}
+
+// Error translates service, storage and helper errors into problem
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, detail := FromError(err)
+	Write(w, r, status, code, detail)
+}
+
+// ImportError writes problem of the error with the report of the stopped import
+func ImportError(w http.ResponseWriter, r *http.Request, err error, report models.ImportReport) {
+	status, code, detail := FromError(err)
+	p := newProblem(w, r, status, code, detail)
+	p.Report = &report
+	write(w, p)
+}
+
+// FromError returns status, code and detail of the problem which matches the error
+func FromError(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout, "operation deadline exceeded"
+	case errors.Is(err, service.ErrCanceled):
+		return statusClientClosedRequest, CodeCanceled, service.ErrCanceled.Error()
+	case errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "request body is too large"
+	case errors.Is(err, service.ErrImportFile):
+		return http.StatusBadRequest, CodeBadRequest, err.Error()
+	case errors.Is(err, service.ErrForbiddenFilter):
+		return http.StatusForbidden, CodeForbidden, service.ErrForbiddenFilter.Error()
+	case errors.Is(err, service.ErrForbiddenSuggest):
+		return http.StatusForbidden, CodeForbidden, service.ErrForbiddenSuggest.Error()
+	case errors.Is(err, confirm.ErrRequired):
+		return http.StatusPreconditionRequired, CodeConfirmRequired, confirm.ErrRequired.Error()
+	case errors.Is(err, confirm.ErrInvalid):
+		return http.StatusConflict, CodeConfirmInvalid, confirm.ErrInvalid.Error()
+	case errors.Is(err, storage.ErrCarNotFound):
+		return http.StatusNotFound, CodeCarNotFound, storage.ErrCarNotFound.Error()
+	// adding doesnt return it, the existing cars are skipped
+	case errors.Is(err, storage.ErrCarExist):
+		return http.StatusConflict, CodeCarExist, storage.ErrCarExist.Error()
+	case errors.Is(err, storage.ErrVinExist):
+		return http.StatusConflict, CodeVinExist, storage.ErrVinExist.Error()
+	case errors.Is(err, storage.ErrCatalogNotFound):
+		return http.StatusNotFound, CodeCatalogNotFound, storage.ErrCatalogNotFound.Error()
+	case errors.Is(err, service.ErrCatalogConflict):
+		return http.StatusConflict, CodeCatalogConflict, err.Error()
+	case errors.Is(err, storage.ErrCatalogExist):
+		return http.StatusConflict, CodeCatalogConflict, storage.ErrCatalogExist.Error()
+	case errors.Is(err, storage.ErrInvalidFilter):
+		return http.StatusBadRequest, CodeBadRequest, storage.ErrInvalidFilter.Error()
+	case errors.Is(err, helper.ErrCarInfoNotFound):
+		return http.StatusUnprocessableEntity, CodeCarInfoNotFound, helper.ErrCarInfoNotFound.Error()
+	case errors.Is(err, service.ErrInvalidCarInfo):
+		return http.StatusBadGateway, CodeCarInfoInvalid, service.ErrInvalidCarInfo.Error()
+	case errors.Is(err, helper.ErrCarInfoUnavailable):
+		return http.StatusBadGateway, CodeUpstreamUnavailable, helper.ErrCarInfoUnavailable.Error()
+	}
+	return http.StatusInternalServerError, CodeInternal, "internal server error"
+}
+
+// requestId returns id of the request which was set by the server middleware,
+// otherwise takes it from header or generates new one
+func requestId(w http.ResponseWriter, r *http.Request) string {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		return id
+	}
+	if id := w.Header().Get(requestid.Header); id != "" {
+		return id
+	}
+	id := r.Header.Get(requestid.Header)
+	if !requestid.IsValid(id) {
+		id = requestid.New()
+	}
+	w.Header().Set(requestid.Header, id)
+	return id
+}
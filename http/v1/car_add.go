@@ -4,55 +4,121 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
 )
 
 type carAdder interface {
-	AddCar(context.Context, []string) error
+	AddCar(context.Context, []string) (models.SaveResult, error)
+}
+
+type fieldValidator interface {
+	ValidateField(string, string) []models.FieldError
 }
 
 // @summary Добавить машину
 // @tags Car
 // @description Добавление машины по ее регистрационному номеру
+// @description
+// @description Существующие машины не изменяются, их идентификаторы возвращаются в skipped. Машины с VIN другой машины не добавляются, их номера возвращаются в vinConflicts. Повторы номера в запросе не добавляются, их номера возвращаются в duplicates. Если ни одна машина не добавлена, возвращается 200
 // @id Car_add
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @accept json
-// @produce plain
+// @produce json
 // @Param regNums body []string true "Регистрационные номера машины" SchemaExample({\n\r "regNums": ["string"]\n\r}) 
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повторный запрос с тем же ключом возвращает сохраненный ответ"
 // @Router /api/cars/add [post]
-// @Success 201
-// @Failure 400
+// @Router /api/v2/cars [post]
+// @Success 201 {object} httpmodels.CarAddResponse
+// @Success 200 {object} httpmodels.CarAddResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 502 {object} problem.Problem
 //
-func CarAdd(logger *slog.Logger, cAdder carAdder) http.HandlerFunc {
-	log := logger.With(slog.String("handler", "add_cars"))
+func CarAdd(logger *slog.Logger, fValidator fieldValidator, cAdder carAdder) http.HandlerFunc {
+	handlerName := slog.String("handler", "add_cars")
+	baseLog := logger.With(handlerName)
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
 		log.Info("attempt to add a cars")
 		req := &httpmodels.CarAddRequest{}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
-			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			problem.BadRequest(w, r, "error while decoding request")
 			return
 		}
 		log.Debug("got data from request", slog.Any("request_body", req))
 		if len(req.RegisterNumbers) == 0 {
 			log.Warn("empty register numbers")
-			http.Error(w, "error while adding car: empty register numbers", http.StatusBadRequest)
+			problem.Validation(w, r, "empty register numbers")
+			return
+		}
+		var errs []models.FieldError
+		for i, regNumber := range req.RegisterNumbers {
+			for _, fieldErr := range fValidator.ValidateField("regNum", regNumber) {
+				fieldErr.Field = fmt.Sprintf("regNums[%d]", i)
+				errs = append(errs, fieldErr)
+			}
+		}
+		if len(errs) != 0 {
+			log.Info("validate error", slog.Any("register_numbers", req.RegisterNumbers), slog.Any("errors", errs))
+			problem.ValidationErrors(w, r, errs)
 			return
 		}
-		err := cAdder.AddCar(context.Background(), req.RegisterNumbers)
+		result, err := cAdder.AddCar(r.Context(), req.RegisterNumbers)
 		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
 			if errors.Is(err, service.ErrGetCarInfo) {
 				log.Warn("failed to get cars info", slog.Any("register_numbers", req.RegisterNumbers), slog.String("error", err.Error()))
-				http.Error(w, "error while adding car", http.StatusBadRequest)
+				problem.Error(w, r, err)
 				return
 			}
 			log.Error("failed to add car", slog.Any("register_numbers", req.RegisterNumbers), slog.String("error", err.Error()))
-			http.Error(w, "error while adding car", http.StatusBadRequest)
+			problem.Error(w, r, err)
 			return
 		}
-		w.WriteHeader(http.StatusCreated)
+		res := &httpmodels.CarAddResponse{
+			Inserted:     result.Inserted,
+			Skipped:      result.Skipped,
+			VinConflicts: make([]string, 0, len(result.VinConflicts)),
+			Duplicates:   make([]string, 0, len(result.Duplicates)),
+		}
+		// positions of the conflicts match the order of the register numbers
+		for _, pos := range result.VinConflicts {
+			res.VinConflicts = append(res.VinConflicts, req.RegisterNumbers[pos])
+		}
+		for _, pos := range result.Duplicates {
+			res.Duplicates = append(res.Duplicates, req.RegisterNumbers[pos])
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		// all cars already exist, so nothing is created
+		if len(result.Inserted) == 0 {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write(resData)
 	}
 }
\ No newline at end of file
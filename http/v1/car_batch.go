@@ -0,0 +1,320 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+)
+
+type carFinder interface {
+	FindCarIds(context.Context, models.CarSelector) ([]int, error)
+}
+
+type carBatchEditor interface {
+	carFinder
+	BatchEditCars(context.Context, models.CarSelector, models.CarForPatch, func([]int) error) ([]int, error)
+}
+
+type carBatchDeleter interface {
+	carFinder
+	BatchDeleteCars(context.Context, models.CarSelector, func([]int) error) ([]int, error)
+}
+
+type confirmSigner interface {
+	Sign(subject string, ids []int) string
+	Verify(token, subject string, ids []int) error
+}
+
+var errTooManyCars = errors.New("too many cars are selected")
+
+// @summary Изменить несколько машин
+// @tags Car
+// @description Изменение данных машин из списка идентификаторов или всех машин, подходящих под фильтр, в одной транзакции.
+// @description Фильтр задается так же, как при получении машин, например {"mark": ["Lada"], "year": ["gt:2000"]}.
+// @description С параметром dryRun машины не изменяются, возвращаются идентификаторы машин, которые будут изменены.
+// @description Регистрационный номер нельзя изменить для нескольких машин.
+// @id Car_batch_edit
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce json
+// @Param dryRun query boolean false "Только посчитать подходящие машины"
+// @Param request body httpmodels.CarBatchEditRequest true "Идентификаторы или фильтр машин и новые данные"
+// @Router /api/v2/cars/batch/edit [post]
+// @Success 200 {object} httpmodels.CarBatchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarBatchEdit(logger *slog.Logger, cValidator carPatchValidator, batchCfg config.BatchConfig, cEditor carBatchEditor, fAdder filterAdder) http.HandlerFunc {
+	handlerName := slog.String("handler", "batch_edit_cars")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to edit cars")
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		req := &httpmodels.CarBatchEditRequest{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, "error while decoding request: "+err.Error())
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		selector, err := parseSelector(req.Ids, req.Filter, fAdder)
+		if err != nil {
+			log.Info("wrong selector", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		newData, errs := parseCarPatch(req.CarNewData)
+		errs = appendFieldErrors(errs, validateBatchPatch(cValidator, newData))
+		if len(errs) != 0 {
+			log.Info("validate error", slog.Any("car_new_data", newData), slog.Any("errors", errs))
+			problem.ValidationErrors(w, r, errs)
+			return
+		}
+		var ids []int
+		if dryRun {
+			ids, err = cEditor.FindCarIds(r.Context(), selector)
+		} else {
+			ids, err = cEditor.BatchEditCars(r.Context(), selector, newData, func(ids []int) error {
+				return checkBatchSize(batchCfg, ids)
+			})
+		}
+		if err != nil {
+			writeBatchError(w, r, log, batchCfg, err)
+			return
+		}
+		writeBatchResponse(w, r, log, httpmodels.CarBatchResponse{
+			DryRun:  dryRun,
+			Matched: len(ids),
+			Ids:     ids,
+		})
+	}
+}
+
+// @summary Удалить несколько машин
+// @tags Car
+// @description Удаление машин из списка идентификаторов или всех машин, подходящих под фильтр, в одной транзакции.
+// @description С параметром dryRun машины не удаляются, возвращаются идентификаторы машин, которые будут удалены.
+// @description Удаление по фильтру требует confirmToken из ответа dryRun, токен недействителен, если подходящие машины изменились.
+// @description Удаление по фильтру доступно, только если оно включено в конфиге, иначе возвращается 403.
+// @id Car_batch_delete
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce json
+// @Param dryRun query boolean false "Только посчитать подходящие машины"
+// @Param request body httpmodels.CarBatchDeleteRequest true "Идентификаторы или фильтр машин"
+// @Router /api/v2/cars/batch/delete [post]
+// @Success 200 {object} httpmodels.CarBatchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarBatchDelete(logger *slog.Logger, batchCfg config.BatchConfig, signer confirmSigner, cDeleter carBatchDeleter, fAdder filterAdder) http.HandlerFunc {
+	handlerName := slog.String("handler", "batch_delete_cars")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to delete cars")
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		req := &httpmodels.CarBatchDeleteRequest{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, "error while decoding request: "+err.Error())
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		selector, err := parseSelector(req.Ids, req.Filter, fAdder)
+		if err != nil {
+			log.Info("wrong selector", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		// only deletes by filter are confirmed, the list of ids is already explicit
+		byFilter := len(selector.Ids) == 0
+		if byFilter && !batchCfg.DeleteByFilter {
+			log.Info("delete by filter is disabled")
+			problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, "delete by filter is disabled")
+			return
+		}
+		subject := confirmSubject(r.Context(), selector.Filter)
+		res := httpmodels.CarBatchResponse{DryRun: dryRun}
+		if dryRun {
+			res.Ids, err = cDeleter.FindCarIds(r.Context(), selector)
+			if err == nil && byFilter && checkBatchSize(batchCfg, res.Ids) == nil {
+				res.ConfirmToken = signer.Sign(subject, res.Ids)
+			}
+		} else {
+			res.Ids, err = cDeleter.BatchDeleteCars(r.Context(), selector, func(ids []int) error {
+				if err := checkBatchSize(batchCfg, ids); err != nil {
+					return err
+				}
+				if byFilter {
+					return signer.Verify(req.ConfirmToken, subject, ids)
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			writeBatchError(w, r, log, batchCfg, err)
+			return
+		}
+		res.Matched = len(res.Ids)
+		writeBatchResponse(w, r, log, res)
+	}
+}
+
+func parseDryRun(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get(dryRunParamName)
+	if value == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("not valid dry run value")
+	}
+	return dryRun, nil
+}
+
+// parseSelector requires either ids or filter, filter errors are terminating
+// because the wrong filter would select more cars
+func parseSelector(ids []int, filterValues map[string][]string, fAdder filterAdder) (models.CarSelector, error) {
+	selector := models.CarSelector{Ids: ids}
+	if len(ids) != 0 && len(filterValues) != 0 {
+		return selector, fmt.Errorf("use ids or filter, not both")
+	}
+	if len(ids) != 0 {
+		return selector, nil
+	}
+	if len(filterValues) == 0 {
+		return selector, fmt.Errorf("ids or filter is required")
+	}
+	for name := range filterValues {
+		if !isFilterField(name) {
+			return selector, fmt.Errorf("unknown filter field %s", name)
+		}
+	}
+	// fields are added in the constant order, so the same filter gives the same confirm token
+	for _, name := range filterFieldNames {
+		for _, value := range filterValues[name] {
+			if err := fAdder(&selector.Filter, name, value); err != nil {
+				return selector, fmt.Errorf("wrong filter %s: %w", name, err)
+			}
+		}
+	}
+	return selector, nil
+}
+
+func isFilterField(name string) bool {
+	for _, fieldName := range filterFieldNames {
+		if fieldName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBatchPatch checks new data of the cars, register number and vin are unique so they cant be set for several cars
+func validateBatchPatch(cValidator carPatchValidator, newData models.CarForPatch) []models.FieldError {
+	var errs []models.FieldError
+	if newData.RegisterNumber != nil {
+		errs = append(errs, models.FieldError{
+			Field:   "regNum",
+			Rule:    models.RuleReadOnly,
+			Message: "register number cant be changed for several cars",
+		})
+	}
+	if newData.Vin != nil {
+		errs = append(errs, models.FieldError{
+			Field:   "vin",
+			Rule:    models.RuleReadOnly,
+			Message: "vin cant be changed for several cars",
+		})
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	if newData.IsEmpty() {
+		return []models.FieldError{{Rule: models.RuleEmpty, Message: "empty car new data"}}
+	}
+	return cValidator.ValidateCarPatch(newData)
+}
+
+func checkBatchSize(batchCfg config.BatchConfig, ids []int) error {
+	if batchCfg.MaxCars > 0 && len(ids) > batchCfg.MaxCars {
+		return errTooManyCars
+	}
+	return nil
+}
+
+// confirmSubject binds the confirm token to the client and to the filter
+func confirmSubject(ctx context.Context, filter models.Filter) string {
+	var subject strings.Builder
+	if principal, ok := auth.FromContext(ctx); ok {
+		subject.WriteString(principal.Name)
+	}
+	for _, field := range filter.Fields {
+		subject.WriteString(fmt.Sprintf("\n%s %s %s %s", field.UnionCondition, field.Name, field.Operator, field.Value))
+	}
+	return subject.String()
+}
+
+func writeBatchError(w http.ResponseWriter, r *http.Request, log *slog.Logger, batchCfg config.BatchConfig, err error) {
+	if errors.Is(err, service.ErrCanceled) {
+		log.Info("request was canceled", slog.String("error", err.Error()))
+		problem.Error(w, r, err)
+		return
+	}
+	if errors.Is(err, errTooManyCars) {
+		log.Info("too many cars are selected", slog.Int("max_cars", batchCfg.MaxCars))
+		problem.Validation(w, r, fmt.Sprintf("%s, max number is %d", errTooManyCars.Error(), batchCfg.MaxCars))
+		return
+	}
+	log.Warn("failed to change cars", slog.String("error", err.Error()))
+	problem.Error(w, r, err)
+}
+
+func writeBatchResponse(w http.ResponseWriter, r *http.Request, log *slog.Logger, res httpmodels.CarBatchResponse) {
+	resData, err := json.Marshal(res)
+	if err != nil {
+		log.Error("cant encode response", slog.String("error", err.Error()))
+		problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resData)
+}
@@ -2,9 +2,13 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
 	"github.com/gorilla/mux"
 )
 
@@ -16,27 +20,41 @@ type carDeleter interface {
 // @tags Car
 // @description Удаление машины по ее идентификатору
 // @id Car_delete
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @produce plain
 // @Param carId path string true "Идентификатор машины"
 // @Router /api/car/{carId}/delete [delete]
+// @Router /api/v2/cars/{carId} [delete]
 // @Success 200
-// @Failure 400
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 //
 func CarDelete(logger *slog.Logger, cDeleter carDeleter) http.HandlerFunc {
-	log := logger.With(slog.String("handler", "delete_car"))
+	handlerName := slog.String("handler", "delete_car")
+	baseLog := logger.With(handlerName)
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
 		log.Info("attempt to delete car")
 		carId, ok := mux.Vars(r)["carId"]
 		if !ok || carId == "" {
 			log.Warn("empty car id")
-			http.Error(w, "error while deleting car: empty car id", http.StatusBadRequest)
+			problem.BadRequest(w, r, "empty car id")
 			return
 		}
 		log.Debug("got car id", slog.String("car_id", carId))
-		err := cDeleter.DeleteCar(context.Background(), carId)
+		err := cDeleter.DeleteCar(r.Context(), carId)
 		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
 			log.Warn("failed to delete the car", slog.String("car_id", carId), slog.String("error", err.Error()))
-			http.Error(w, "error while deleting car", http.StatusBadRequest)
+			problem.Error(w, r, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,411 @@
+package v1
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+)
+
+type carExporter interface {
+	ExportCars(context.Context, models.PaginationOption, models.Filter, func(models.Car) error) error
+}
+
+// export formats
+const (
+	formatCsv    = "csv"
+	formatNdjson = "ndjson"
+
+	contentTypeCsv    = "text/csv"
+	contentTypeNdjson = "application/x-ndjson"
+
+	formatParamName = "format"
+
+	defaultFlushRows = 100
+)
+
+// json names of all car fields in the export order
+var carFieldNames = []string{
+	"carId",
+	"regNum",
+	"mark",
+	"model",
+	"year",
+	"owner",
+	"plate",
+	"vin",
+	"vinInfo",
+	"source",
+	"fetchedAt",
+	"createdAt",
+	"updatedAt",
+	"updatedBy",
+}
+
+// owner is exported to csv as three columns
+var ownerCsvColumns = []string{"ownerName", "ownerSurname", "ownerPatronymic"}
+
+// plate is exported to csv as four columns
+var plateCsvColumns = []string{"plateType", "plateSeries", "plateNumber", "plateRegion"}
+
+// vin info is exported to csv as four columns
+var vinInfoCsvColumns = []string{"vinWmi", "vinManufacturer", "vinRegion", "vinModelYear"}
+
+// carEncoder writes cars to the response one by one
+type carEncoder interface {
+	begin() error
+	encode(models.Car) error
+	flush() error
+}
+
+type csvCarEncoder struct {
+	writer         *csv.Writer
+	columns        []string
+	headers        map[string]string
+	escapeFormulas bool
+}
+
+func (ce *csvCarEncoder) begin() error {
+	titles := make([]string, 0, len(ce.columns))
+	for _, column := range ce.columns {
+		title, ok := ce.headers[column]
+		if !ok {
+			title = column
+		}
+		titles = append(titles, title)
+	}
+	return ce.writer.Write(titles)
+}
+
+func (ce *csvCarEncoder) encode(car models.Car) error {
+	record := make([]string, 0, len(ce.columns))
+	for _, column := range ce.columns {
+		value := csvValue(car, column)
+		if ce.escapeFormulas {
+			value = escapeCsvFormula(value)
+		}
+		record = append(record, value)
+	}
+	return ce.writer.Write(record)
+}
+
+// escapeCsvFormula prefixes the value by the quote if spreadsheets would evaluate it as formula
+func escapeCsvFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (ce *csvCarEncoder) flush() error {
+	ce.writer.Flush()
+	return ce.writer.Error()
+}
+
+type ndjsonCarEncoder struct {
+	encoder *json.Encoder
+	fields  []string
+}
+
+func (ne *ndjsonCarEncoder) begin() error {
+	return nil
+}
+
+func (ne *ndjsonCarEncoder) encode(car models.Car) error {
+	if len(ne.fields) == 0 {
+		return ne.encoder.Encode(car)
+	}
+	sparse, err := sparseCar(car, ne.fields)
+	if err != nil {
+		return err
+	}
+	return ne.encoder.Encode(sparse)
+}
+
+func (ne *ndjsonCarEncoder) flush() error {
+	return nil
+}
+
+// @summary Выгрузить машины
+// @tags Car
+// @description Потоковая выгрузка машин с фильтром в формате CSV или NDJSON.
+// @description Формат выбирается параметром format или заголовком Accept (text/csv, application/x-ndjson), по умолчанию CSV.
+// @description Фильтры, сортировка и выбор полей такие же, как при получении машин.
+// @id Car_export
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce text/csv
+// @produce application/x-ndjson
+// @Param format query string false "Формат выгрузки (csv, ndjson)"
+// @Param fields query string false "Выгружаемые поля машины через запятую" example(regNum,mark)
+// @Param sort query []string false "Сортировка в формате col_name:direction (asc/desc)" collectionFormat(multi)
+// @Router /api/v2/cars/export [get]
+// @Success 200 {string} string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 406 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarExport(logger *slog.Logger, exportCfg config.ExportConfig, exporter carExporter, fAdder filterAdder, sAdder sortAdder, fsAdder fieldsAdder) http.HandlerFunc {
+	handlerName := slog.String("handler", "export_cars")
+	baseLog := logger.With(handlerName)
+	delimiter := ','
+	if exportCfg.CsvDelimiter != "" {
+		delimiter, _ = utf8.DecodeRuneInString(exportCfg.CsvDelimiter)
+	}
+	flushRows := exportCfg.FlushRows
+	if flushRows <= 0 {
+		flushRows = defaultFlushRows
+	}
+	// columns from config are checked once, the wrong ones are skipped
+	defaultOption := models.PaginationOption{}
+	for _, column := range exportCfg.Columns {
+		if err := fsAdder(&defaultOption, column); err != nil {
+			baseLog.Warn("wrong export column in config", slog.String("column", column), slog.String("error", err.Error()))
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to export cars")
+		format, ok := exportFormat(r)
+		if !ok {
+			log.Info("not acceptable export format", slog.String("accept", r.Header.Get("Accept")))
+			problem.Write(w, r, http.StatusNotAcceptable, problem.CodeNotAcceptable,
+				"supported formats are "+contentTypeCsv+" and "+contentTypeNdjson)
+			return
+		}
+		var (
+			pagOption models.PaginationOption
+			filter    models.Filter
+		)
+		if err := parseListQuery(r, log, &pagOption, &filter, fAdder, sAdder, fsAdder); err != nil {
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		if len(pagOption.Fields) == 0 {
+			pagOption.Fields = defaultOption.Fields
+		}
+		var (
+			encoder     carEncoder
+			contentType string
+		)
+		switch format {
+		case formatCsv:
+			writer := csv.NewWriter(w)
+			writer.Comma = delimiter
+			encoder = &csvCarEncoder{
+				writer:         writer,
+				columns:        csvColumns(pagOption.Fields),
+				headers:        exportCfg.CsvHeaders,
+				escapeFormulas: exportCfg.CsvEscapeFormulas,
+			}
+			contentType = contentTypeCsv + "; charset=utf-8"
+		default:
+			encoder = &ndjsonCarEncoder{
+				encoder: json.NewEncoder(w),
+				fields:  pagOption.Fields,
+			}
+			contentType = contentTypeNdjson
+		}
+		rc := http.NewResponseController(w)
+		// export can last longer than the write timeout of the server
+		rc.SetWriteDeadline(time.Time{})
+		started := false
+		start := func() error {
+			started = true
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", `attachment; filename="cars.`+format+`"`)
+			w.WriteHeader(http.StatusOK)
+			return encoder.begin()
+		}
+		rows := 0
+		err := exporter.ExportCars(r.Context(), pagOption, filter, func(car models.Car) error {
+			if !started {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			if err := encoder.encode(car); err != nil {
+				return err
+			}
+			rows++
+			if rows%flushRows == 0 {
+				if err := encoder.flush(); err != nil {
+					return err
+				}
+				// write error of the gone client is returned by the next rows
+				rc.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			if !started {
+				if errors.Is(err, service.ErrCanceled) {
+					log.Info("request was canceled", slog.String("error", err.Error()))
+				} else {
+					log.Error("failed to export cars", slog.String("error", err.Error()))
+				}
+				problem.Error(w, r, err)
+				return
+			}
+			// status is already sent, so the connection is aborted to show the client that the export isnt complete
+			log.Error("export is interrupted", slog.Int("rows", rows), slog.String("error", err.Error()))
+			panic(http.ErrAbortHandler)
+		}
+		if !started {
+			if err := start(); err != nil {
+				log.Error("failed to write export header", slog.String("error", err.Error()))
+				return
+			}
+		}
+		if err := encoder.flush(); err != nil {
+			log.Error("failed to flush export", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// exportFormat chooses format by the query parameter or by the first supported type from Accept header
+func exportFormat(r *http.Request) (string, bool) {
+	switch r.URL.Query().Get(formatParamName) {
+	case formatCsv:
+		return formatCsv, true
+	case formatNdjson:
+		return formatNdjson, true
+	case "":
+	default:
+		return "", false
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formatCsv, true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		switch strings.TrimSpace(mediaType) {
+		case contentTypeCsv, "text/*", "*/*":
+			return formatCsv, true
+		case contentTypeNdjson:
+			return formatNdjson, true
+		}
+	}
+	return "", false
+}
+
+// csvColumns expands owner, plate and vin info fields to their columns, empty fields mean all fields
+func csvColumns(fields []string) []string {
+	if len(fields) == 0 {
+		fields = carFieldNames
+	}
+	columns := make([]string, 0, len(fields)+len(ownerCsvColumns)+len(plateCsvColumns)+len(vinInfoCsvColumns))
+	for _, field := range fields {
+		switch field {
+		case embedOwner:
+			columns = append(columns, ownerCsvColumns...)
+		case "plate":
+			columns = append(columns, plateCsvColumns...)
+		case "vinInfo":
+			columns = append(columns, vinInfoCsvColumns...)
+		default:
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+func csvValue(car models.Car, column string) string {
+	switch column {
+	case "carId":
+		return strconv.Itoa(car.Id)
+	case "regNum":
+		return car.RegisterNumber
+	case "mark":
+		return car.Mark
+	case "model":
+		return car.Model
+	case "year":
+		return strconv.Itoa(int(car.Year))
+	case "ownerName":
+		if car.Owner != nil {
+			return car.Owner.Name
+		}
+	case "ownerSurname":
+		if car.Owner != nil {
+			return car.Owner.Surname
+		}
+	case "ownerPatronymic":
+		if car.Owner != nil && car.Owner.Patronymic != nil {
+			return *car.Owner.Patronymic
+		}
+	case "plateType":
+		if car.Plate != nil {
+			return car.Plate.Type
+		}
+	case "plateSeries":
+		if car.Plate != nil {
+			return car.Plate.Series
+		}
+	case "plateNumber":
+		if car.Plate != nil {
+			return car.Plate.Number
+		}
+	case "plateRegion":
+		if car.Plate != nil {
+			return car.Plate.Region
+		}
+	case "vin":
+		if car.Vin != nil {
+			return *car.Vin
+		}
+	case "vinWmi":
+		if car.VinInfo != nil {
+			return car.VinInfo.Wmi
+		}
+	case "vinManufacturer":
+		if car.VinInfo != nil {
+			return car.VinInfo.Manufacturer
+		}
+	case "vinRegion":
+		if car.VinInfo != nil {
+			return car.VinInfo.Region
+		}
+	case "vinModelYear":
+		if car.VinInfo != nil && car.VinInfo.ModelYear != nil {
+			return strconv.Itoa(*car.VinInfo.ModelYear)
+		}
+	case "source":
+		return car.Source
+	case "fetchedAt":
+		if car.FetchedAt != nil {
+			return car.FetchedAt.Format(time.RFC3339)
+		}
+	case "createdAt":
+		return formatTime(car.CreatedAt)
+	case "updatedAt":
+		return formatTime(car.UpdatedAt)
+	case "updatedBy":
+		if car.UpdatedBy != nil {
+			return *car.UpdatedBy
+		}
+	}
+	return ""
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestCsvCarEncoderEscapeFormulas(t *testing.T) {
+	car := models.Car{
+		Id:             1,
+		RegisterNumber: "X123XX150",
+		Mark:           "=HYPERLINK(\"http://evil\")",
+		Model:          "-Vesta",
+		Year:           2002,
+		Owner: &models.Owner{
+			Name:    "@Ivan",
+			Surname: "+Ivanov",
+		},
+	}
+	tests := []struct {
+		name   string
+		escape bool
+		want   string
+	}{
+		{
+			name:   "escaped",
+			escape: true,
+			want:   "1,X123XX150,\"'=HYPERLINK(\"\"http://evil\"\")\",'-Vesta,2002,'@Ivan,'+Ivanov\n",
+		},
+		{
+			name: "not escaped",
+			want: "1,X123XX150,\"=HYPERLINK(\"\"http://evil\"\")\",-Vesta,2002,@Ivan,+Ivanov\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			encoder := &csvCarEncoder{
+				writer:         csv.NewWriter(&buf),
+				columns:        []string{"carId", "regNum", "mark", "model", "year", "ownerName", "ownerSurname"},
+				escapeFormulas: tt.escape,
+			}
+			if err := encoder.encode(car); err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			if err := encoder.flush(); err != nil {
+				t.Fatalf("flush() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeCsvFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Lada", "Lada"},
+		{"=1+2", "'=1+2"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := escapeCsvFormula(tt.value); got != tt.want {
+			t.Errorf("escapeCsvFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
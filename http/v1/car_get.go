@@ -3,12 +3,21 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/cache"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
 	"github.com/gorilla/mux"
 )
 
@@ -16,12 +25,20 @@ type carOneGetter interface {
 	GetOneCar(ctx context.Context, carId string) (models.Car, error)
 }
 
+type carVinGetter interface {
+	GetCarByVin(ctx context.Context, vin string) (models.Car, error)
+}
+
 type carAllGetter interface {
 	GetAllCars(context.Context, models.PaginationOption, models.Filter) ([]models.Car, error)
 }
 
 type filterAdder func(filter *models.Filter, name, value string) error
 
+type sortAdder func(pgOption *models.PaginationOption, value string) error
+
+type fieldsAdder func(pgOption *models.PaginationOption, value string) error
+
 // query filter fields name
 const (
 	regNumberFieldName = "reg_num"
@@ -31,33 +48,92 @@ const (
 	ownerNameFieldName = "owner_name"
 	ownerSurnameFieldName = "owner_surname"
 	ownerPatronymicFieldName = "owner_patronymic"
+	plateTypeFieldName = "plate_type"
+	plateRegionFieldName = "plate_region"
+	vinFieldName = "vin"
+	sourceFieldName = "source"
+	fetchedAtFieldName = "fetched_at"
+	createdAtFieldName = "created_at"
+	updatedAtFieldName = "updated_at"
+	updatedByFieldName = "updated_by"
 )
 
+var filterFieldNames = []string{
+	regNumberFieldName,
+	markFieldName,
+	modelFieldName,
+	yearFieldName,
+	ownerNameFieldName,
+	ownerSurnameFieldName,
+	ownerPatronymicFieldName,
+	plateTypeFieldName,
+	plateRegionFieldName,
+	vinFieldName,
+	sourceFieldName,
+	fetchedAtFieldName,
+	createdAtFieldName,
+	updatedAtFieldName,
+	updatedByFieldName,
+}
+
+// query sort, fields and embed parameters name
+const (
+	sortFieldName = "sort"
+	fieldsParamName = "fields"
+	embedParamName = "embed"
+)
+
+// related data which can be embedded into the car with embed parameter
+const (
+	embedOwner = "owner"
+	embedHistory = "history"
+)
+
+// provenance fields of the car: the source of the data and the latest change, the older changes arent stored
+const historyFields = "source,updatedAt,updatedBy"
+
 // @summary Получить данные машины
 // @tags Car
 // @description Получение данных машины по ее идентификатору
 // @id Car_get_one
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @produce json
 // @Param carId path string true "Идентификатор машины"
 // @Router /api/car/{carId} [get]
+// @Router /api/v2/cars/{carId} [get]
+// @Param If-None-Match header string false "ETag ранее полученного ответа"
+// @Param If-Modified-Since header string false "Время последнего изменения ранее полученного ответа"
 // @Success 200 {object} httpmodels.CarGetOneResponse
-// @Failure 400
+// @Success 304
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 //
 func CarGetOne(logger *slog.Logger, carGetter carOneGetter) http.HandlerFunc {
-	log := logger.With(slog.String("handler", "get_one_car"))
+	handlerName := slog.String("handler", "get_one_car")
+	baseLog := logger.With(handlerName)
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
 		log.Info("attempt to get one car")
 		carId, ok := mux.Vars(r)["carId"]
 		if !ok || carId == "" {
 			log.Warn("failed to get car id")
-			http.Error(w, "error while getting car: empty car id", http.StatusBadRequest)
+			problem.BadRequest(w, r, "empty car id")
 			return
 		}
 		log.Debug("got car id", slog.String("car_id", carId))
-		car, err := carGetter.GetOneCar(context.Background(), carId)
+		car, err := carGetter.GetOneCar(r.Context(), carId)
 		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
 			log.Error("failed to get car", slog.String("error", err.Error()))
-			http.Error(w, "error while getting car", http.StatusBadRequest)
+			problem.Error(w, r, err)
 			return
 		}
 		log.Debug("got car", slog.Any("car", car))
@@ -67,12 +143,68 @@ func CarGetOne(logger *slog.Logger, carGetter carOneGetter) http.HandlerFunc {
 		resData, err := json.Marshal(res)
 		if err != nil {
 			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
-			http.Error(w, "error while getting car", http.StatusInternalServerError)
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
 			return
 		}
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(resData)
+		cache.WriteJSON(w, r, resData, car.UpdatedAt)
+	}
+}
+
+// @summary Получить данные машины по VIN
+// @tags Car
+// @description Получение данных машины по VIN, регистр VIN не учитывается
+// @id Car_get_by_vin
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce json
+// @Param vin path string true "VIN машины" example(XTA21099043576182)
+// @Router /api/cars/by-vin/{vin} [get]
+// @Router /api/v2/cars/by-vin/{vin} [get]
+// @Param If-None-Match header string false "ETag ранее полученного ответа"
+// @Param If-Modified-Since header string false "Время последнего изменения ранее полученного ответа"
+// @Success 200 {object} httpmodels.CarGetOneResponse
+// @Success 304
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarGetByVin(logger *slog.Logger, fValidator fieldValidator, carGetter carVinGetter) http.HandlerFunc {
+	handlerName := slog.String("handler", "get_car_by_vin")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to get car by vin")
+		vin := mux.Vars(r)["vin"]
+		log.Debug("got vin", slog.String("vin", vin))
+		if fieldErrs := fValidator.ValidateField("vin", vin); len(fieldErrs) != 0 {
+			log.Info("not valid vin", slog.String("vin", vin), slog.Any("errors", fieldErrs))
+			problem.BadRequest(w, r, fieldErrs[0].Message)
+			return
+		}
+		car, err := carGetter.GetCarByVin(r.Context(), vin)
+		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
+			log.Error("failed to get car", slog.String("error", err.Error()))
+			problem.Error(w, r, err)
+			return
+		}
+		log.Debug("got car", slog.Any("car", car))
+		res := &httpmodels.CarGetOneResponse{
+			Car: car,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+			return
+		}
+		cache.WriteJSON(w, r, resData, car.UpdatedAt)
 	}
 }
 
@@ -87,6 +219,8 @@ func CarGetOne(logger *slog.Logger, carGetter carOneGetter) http.HandlerFunc {
 // @description Operator(необязательный) - логический оператор (eq,neq,gt,get,lt,let,like) (по умолчанию eq)
 // @description Value(обязательный) - само значение для фильтра
 // @id Car_get_all
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @produce json
 // @Param reg_nums query []string false "Фильтр для поля регистрационного номера" example(like:X123XX150) collectionFormat(multi)
 // @Param marks query []string false "Фильтр для поля марки" example(or:like:Lada) collectionFormat(multi)
@@ -95,15 +229,38 @@ func CarGetOne(logger *slog.Logger, carGetter carOneGetter) http.HandlerFunc {
 // @Param owner_name query []string false "Фильтр для поля имени владельца" collectionFormat(multi)
 // @Param owner_surname query []string false "Фильтр для поля фамилии владельца" collectionFormat(multi)
 // @Param owner_patronymic query []string false "Фильтр для поля отчества владельца" collectionFormat(multi)
+// @Param plate_type query []string false "Фильтр для типа номерного знака (private, taxi, trailer, motorcycle, diplomatic, military)" collectionFormat(multi)
+// @Param plate_region query []string false "Фильтр для кода региона номерного знака" example(77) collectionFormat(multi)
+// @Param vin query []string false "Фильтр для VIN (хранится в верхнем регистре)" example(like:XTA%) collectionFormat(multi)
+// @Param source query []string false "Фильтр для поля источника записи (external_api, manual, import, resync)" collectionFormat(multi)
+// @Param fetched_at query []string false "Фильтр для поля времени получения данных из внешнего API" example(gt:2024-01-01T00:00:00Z) collectionFormat(multi)
+// @Param created_at query []string false "Фильтр для поля времени создания записи" collectionFormat(multi)
+// @Param updated_at query []string false "Фильтр для поля времени последнего изменения записи" collectionFormat(multi)
+// @Param updated_by query []string false "Фильтр для поля последнего редактора записи" collectionFormat(multi)
+// @Param sort query []string false "Сортировка в формате col_name:direction (asc/desc)" example(created_at:desc) collectionFormat(multi)
+// @Param fields query string false "Возвращаемые поля машины через запятую" example(regNum,mark)
+// @Param embed query string false "Связанные данные, добавляемые к выбранным полям: owner - владелец, history - поля происхождения записи (source, updatedAt, updatedBy), прошлые изменения не хранятся. Требует fields" example(owner)
 // @Param limit query integer false "Количество записей на странице" minimum(1)
 // @Param offset query integer false "Количество пропущенных записей"
+// @Param facets query string false "Фасеты отфильтрованных машин через запятую (mark, model, year, plate_region)" example(mark,year)
+// @Param aggregates query string false "Агрегаты значений фасетов через запятую (avg_age, min_year, max_year)"
+// @Param year_bucket query integer false "Размер интервала годов для фасета year" minimum(1) maximum(100) default(1)
+// @Param facet_limit query integer false "Количество самых частых значений фасета" minimum(1)
 // @Router /api/cars [get]
+// @Router /api/v2/cars [get]
+// @Param If-None-Match header string false "ETag ранее полученного ответа"
 // @Success 200 {object} httpmodels.CarGetAllResponse
-// @Failure 400
+// @Success 304
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 //
-func CarGetAll(logger *slog.Logger, carGetter carAllGetter, fAdder filterAdder) http.HandlerFunc {
-	log := logger.With(slog.String("handler", "get_all_cars"))
+func CarGetAll(logger *slog.Logger, statsCfg config.StatsConfig, carGetter carAllGetter, statsGetter carStatsGetter, fAdder filterAdder, sAdder sortAdder, fsAdder fieldsAdder) http.HandlerFunc {
+	handlerName := slog.String("handler", "get_all_cars")
+	baseLog := logger.With(handlerName)
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
 		log.Info("attempt to get all cars")
 		var (
 			pagOption models.PaginationOption
@@ -119,117 +276,171 @@ func CarGetAll(logger *slog.Logger, carGetter carAllGetter, fAdder filterAdder)
 			oint, _ := strconv.Atoi(offset)
 			pagOption.Limit = oint
 		}
-		queries := r.URL.Query()
-		regNumbers := queries[regNumberFieldName]
-		if len(regNumbers) != 0 {
-			for i := 0; i < len(regNumbers); i++ {
-				// make error terminating
-				err = fAdder(&filter, regNumberFieldName, regNumbers[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", regNumberFieldName),
-					slog.String("value", regNumbers[i]),
-					slog.String("error", err.Error()))
-				}
+		if err = parseListQuery(r, log, &pagOption, &filter, fAdder, sAdder, fsAdder); err != nil {
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		// facets are counted for all filtered cars, not only for the page
+		statsOption, err := parseStatsQuery(r.URL.Query(), statsCfg)
+		if err != nil {
+			log.Info("wrong stats parameters", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		cars, err := carGetter.GetAllCars(r.Context(), pagOption, filter)
+		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
 			}
+			log.Error("failed to get cars", slog.String("error", err.Error()))
+			problem.Error(w, r, err)
+			return
 		}
-
-		marks := queries[markFieldName]
-		if len(marks) != 0 {
-			for i := 0; i < len(marks); i++ {
-				err = fAdder(&filter, markFieldName, marks[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", markFieldName),
-					slog.String("value", marks[i]),
-					slog.String("error", err.Error()))
+		log.Debug("got cars", slog.Any("cars", cars))
+		var facets map[string][]models.FacetValue
+		if len(statsOption.Facets) != 0 {
+			stats, err := statsGetter.GetCarStats(r.Context(), filter, statsOption)
+			if err != nil {
+				if errors.Is(err, service.ErrCanceled) {
+					log.Info("request was canceled", slog.String("error", err.Error()))
+					problem.Error(w, r, err)
+					return
 				}
+				log.Error("failed to get cars facets", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
 			}
+			facets = stats.Facets
 		}
-
-		models := queries[modelFieldName]
-		if len(models) != 0 {
-			for i := 0; i < len(models); i++ {
-				err = fAdder(&filter, modelFieldName, models[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", modelFieldName),
-					slog.String("value", models[i]),
-					slog.String("error", err.Error()))
-				}
+		var res interface{} = &httpmodels.CarGetAllResponse{
+			Cars: cars,
+			Facets: facets,
+		}
+		if len(pagOption.Fields) != 0 {
+			sparse, err := sparseCars(cars, pagOption.Fields)
+			if err != nil {
+				log.Error("cant select car fields", slog.String("error", err.Error()))
+				problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+				return
 			}
+			sparse.Facets = facets
+			res = sparse
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+			return
+		}
+		// deleted cars dont change updated_at of the rest, so only ETag is used for the list
+		cache.WriteJSON(w, r, resData, time.Time{})
+	}
+}
+// sparseCars keeps only the selected fields of the cars in the response
+func sparseCars(cars []models.Car, fields []string) (*httpmodels.CarGetAllSparseResponse, error) {
+	res := &httpmodels.CarGetAllSparseResponse{
+		Cars: make([]map[string]json.RawMessage, 0, len(cars)),
+	}
+	for _, car := range cars {
+		sparse, err := sparseCar(car, fields)
+		if err != nil {
+			return nil, err
 		}
+		res.Cars = append(res.Cars, sparse)
+	}
+	return res, nil
+}
 
-		year := queries[yearFieldName]
-		if len(year) != 0 {
-			for i := 0; i < len(year); i++ {
-				err = fAdder(&filter, yearFieldName, year[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", yearFieldName),
-					slog.String("value", year[i]),
-					slog.String("error", err.Error()))
-				}
-			}
+// sparseCar returns json values of the selected car fields
+func sparseCar(car models.Car, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(car)
+	if err != nil {
+		return nil, err
+	}
+	full := map[string]json.RawMessage{}
+	if err = json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+	sparse := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		// owner is omitted if the client doesnt have access to it
+		if value, ok := full[field]; ok {
+			sparse[field] = value
 		}
+	}
+	return sparse, nil
+}
 
-		ownerNames := queries[ownerNameFieldName]
-		if len(ownerNames) != 0 {
-			for i := 0; i < len(ownerNames); i++ {
-				err = fAdder(&filter, ownerNameFieldName, ownerNames[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", ownerNameFieldName),
-					slog.String("value", ownerNames[i]),
-					slog.String("error", err.Error()))
-				}
-			}
+// parseListQuery fills filter, sorting and selected fields from the query,
+// wrong filters and sorting are skipped, wrong fields and embeds are returned as error.
+// Embeds are added to the selected fields, so they arent accepted without fields
+func parseListQuery(r *http.Request, log *slog.Logger, pagOption *models.PaginationOption, filter *models.Filter, fAdder filterAdder, sAdder sortAdder, fsAdder fieldsAdder) error {
+	queries := r.URL.Query()
+	// the list keeps skipping wrong filters for the old clients
+	_ = parseFilterQuery(r, log, filter, fAdder)
+
+	sortValues := queries[sortFieldName]
+	for i := 0; i < len(sortValues); i++ {
+		err := sAdder(pagOption, sortValues[i])
+		if err != nil {
+			log.Warn("wrong sort",
+			slog.String("value", sortValues[i]),
+			slog.String("error", err.Error()))
 		}
+	}
 
-		ownerSurnames := queries[ownerSurnameFieldName]
-		if len(ownerSurnames) != 0 {
-			for i := 0; i < len(ownerSurnames); i++ {
-				err = fAdder(&filter, ownerSurnameFieldName, ownerSurnames[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", ownerSurnameFieldName),
-					slog.String("value", ownerSurnames[i]),
-					slog.String("error", err.Error()))
-				}
+	for _, value := range queries[fieldsParamName] {
+		if err := fsAdder(pagOption, value); err != nil {
+			log.Info("wrong fields", slog.String("value", value), slog.String("error", err.Error()))
+			return err
+		}
+	}
+	embeds := queries[embedParamName]
+	if len(embeds) != 0 && len(pagOption.Fields) == 0 {
+		err := fmt.Errorf("%s requires %s parameter", embedParamName, fieldsParamName)
+		log.Info("wrong embed", slog.Any("value", embeds), slog.String("error", err.Error()))
+		return err
+	}
+	for _, value := range embeds {
+		for _, embed := range strings.Split(value, ",") {
+			var err error
+			switch strings.TrimSpace(embed) {
+			case embedOwner:
+				err = fsAdder(pagOption, embedOwner)
+			case embedHistory:
+				err = fsAdder(pagOption, historyFields)
+			default:
+				err = fmt.Errorf("unknown embed %s", embed)
+			}
+			if err != nil {
+				log.Info("wrong embed", slog.String("value", value), slog.String("error", err.Error()))
+				return err
 			}
 		}
+	}
+	return nil
+}
 
-		ownerPatronymics := queries[ownerPatronymicFieldName]
-		if len(ownerPatronymics) != 0 {
-			for i := 0; i < len(ownerPatronymics); i++ {
-				err = fAdder(&filter, ownerPatronymicFieldName, ownerPatronymics[i])
-				if err != nil {
-					log.Warn("wrong filter",
-					slog.String("field", ownerPatronymicFieldName),
-					slog.String("value", ownerPatronymics[i]),
-					slog.String("error", err.Error()))
+// parseFilterQuery fills filter from the query, wrong filters are skipped and the first error is returned
+func parseFilterQuery(r *http.Request, log *slog.Logger, filter *models.Filter, fAdder filterAdder) error {
+	queries := r.URL.Query()
+	var firstErr error
+	for _, fieldName := range filterFieldNames {
+		values := queries[fieldName]
+		for i := 0; i < len(values); i++ {
+			err := fAdder(filter, fieldName, values[i])
+			if err != nil {
+				log.Warn("wrong filter",
+				slog.String("field", fieldName),
+				slog.String("value", values[i]),
+				slog.String("error", err.Error()))
+				if firstErr == nil {
+					firstErr = fmt.Errorf("wrong filter %s: %w", fieldName, err)
 				}
 			}
 		}
-
-		cars, err := carGetter.GetAllCars(context.Background(), pagOption, filter)
-		if err != nil {
-			log.Error("failed to get cars", slog.String("error", err.Error()))
-			http.Error(w, "error while getting cars", http.StatusBadRequest)
-			return
-		}
-		log.Debug("got cars", slog.Any("cars", cars))
-		res := &httpmodels.CarGetAllResponse{
-			Cars: cars,
-		}
-		resData, err := json.Marshal(res)
-		if err != nil {
-			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
-			http.Error(w, "error while getting cars", http.StatusInternalServerError)
-			return
-		}
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(resData)
 	}
-}
\ No newline at end of file
+	return firstErr
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage/postgres"
+)
+
+func TestParseListQueryEmbed(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{query: "fields=regNum&embed=owner", want: []string{"regNum", "owner"}},
+		{query: "embed=owner,history&fields=regNum", want: []string{"regNum", "owner", "source", "updatedAt", "updatedBy"}},
+		{query: "fields=regNum,updatedAt&embed=history", want: []string{"regNum", "updatedAt", "source", "updatedBy"}},
+		{query: "embed=owner,history", wantErr: true},
+		{query: "fields=&embed=owner", wantErr: true},
+		{query: "fields=regNum&embed=color", wantErr: true},
+		{query: "fields=color", wantErr: true},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v2/cars?"+tt.query, nil)
+			var (
+				pagOption models.PaginationOption
+				filter    models.Filter
+			)
+			err := parseListQuery(r, log, &pagOption, &filter, postgres.AddFilter, postgres.AddSort, postgres.AddFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseListQuery() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(pagOption.Fields, tt.want) {
+				t.Errorf("fields = %v, want %v", pagOption.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestSparseCars(t *testing.T) {
+	patronymic := "I***"
+	cars := []models.Car{
+		{Id: 1, RegisterNumber: "Х123ХХ150", Mark: "Lada", Owner: &models.Owner{Name: "I***", Surname: "I***", Patronymic: &patronymic}},
+		// owner is removed by the access of the principal
+		{Id: 2, RegisterNumber: "А123ВС77", Mark: "Lada"},
+	}
+	res, err := sparseCars(cars, []string{"regNum", "owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(res.Cars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"owner":{"name":"I***","surname":"I***","patronymic":"I***"},"regNum":"Х123ХХ150"},{"regNum":"А123ВС77"}]`
+	if string(data) != want {
+		t.Errorf("sparse cars = %s, want %s", data, want)
+	}
+}
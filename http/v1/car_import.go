@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/importer"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+)
+
+type carImporter interface {
+	ImportCars(context.Context, importer.RowReader, bool, models.ConflictPolicy) (models.ImportReport, error)
+}
+
+const (
+	dryRunParamName     = "dryRun"
+	onConflictParamName = "onConflict"
+)
+
+// @summary Импортировать машины
+// @tags Car
+// @description Импорт полных записей машин из CSV или NDJSON без обращения к внешнему API.
+// @description Формат выбирается параметром format или заголовком Content-Type (text/csv, application/x-ndjson).
+// @description CSV должен содержать заголовок с колонками regNum, mark, model, year, ownerName, ownerSurname и необязательной ownerPatronymic.
+// @description Строки NDJSON содержат поля regNum, mark, model, year, owner и необязательное vin, строки с полями только для чтения (carId, source, createdAt и др.) или неизвестными полями невалидны.
+// @description Каждая строка проверяется, в ответе возвращаются ошибки невалидных строк.
+// @description Существующие машины пропускаются (skip), обновляются (update) или импорт прерывается с ошибкой (fail) в зависимости от параметра onConflict. При обновлении VIN и номерной знак, которых нет в строке, сохраняются.
+// @description Сохраненные до ошибки пакеты не откатываются, поэтому ошибка содержит отчет (report) со строкой остановки импорта (stoppedAtLine).
+// @id Car_import
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept text/csv
+// @accept application/x-ndjson
+// @produce json
+// @Param format query string false "Формат файла (csv, ndjson)"
+// @Param dryRun query boolean false "Только проверить файл, не сохраняя машины"
+// @Param onConflict query string false "Действие с существующими машинами (skip, update, fail), по умолчанию skip"
+// @Param file body string true "Содержимое файла"
+// @Router /api/v2/cars/import [post]
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarImport(logger *slog.Logger, importCfg config.ImportConfig, cValidator importer.CarValidator, cImporter carImporter) http.HandlerFunc {
+	handlerName := slog.String("handler", "import_cars")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to import cars")
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		policy := models.ConflictSkip
+		if value := r.URL.Query().Get(onConflictParamName); value != "" {
+			policy = models.ConflictPolicy(value)
+			if !policy.Valid() {
+				problem.BadRequest(w, r, "not valid conflict policy, use skip, update or fail")
+				return
+			}
+		}
+		rc := http.NewResponseController(w)
+		// upload of the big file can last longer than the timeouts of the server
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+		format := importFormat(r)
+		if importCfg.MaxBodySize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, importCfg.MaxBodySize)
+		}
+		rows, err := importer.NewRowReader(format, r.Body, cValidator)
+		if err != nil {
+			log.Info("unsupported import format", slog.String("content_type", r.Header.Get("Content-Type")))
+			problem.Write(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia,
+				"supported formats are "+contentTypeCsv+" and "+contentTypeNdjson)
+			return
+		}
+		report, err := cImporter.ImportCars(r.Context(), rows, dryRun, policy)
+		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.ImportError(w, r, err, report)
+				return
+			}
+			log.Warn("failed to import cars", slog.Int("saved", report.Saved), slog.Int("stopped_at_line", report.StoppedAtLine),
+				slog.String("error", err.Error()))
+			problem.ImportError(w, r, err, report)
+			return
+		}
+		resData, err := json.Marshal(report)
+		if err != nil {
+			log.Error("cant encode response", slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// importFormat chooses format by the query parameter or by Content-Type header
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get(formatParamName); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case contentTypeCsv:
+		return importer.FormatCsv
+	case contentTypeNdjson:
+		return importer.FormatNdjson
+	}
+	return mediaType
+}
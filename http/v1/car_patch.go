@@ -0,0 +1,210 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+// content type of JSON Merge Patch (RFC 7396), its body is the changed part of the car itself
+const contentTypeMergePatch = "application/merge-patch+json"
+
+// fields of the car which are set by the service and cant be changed by the client
+var readOnlyCarFields = map[string]struct{}{
+	"carId":     {},
+	"plate":     {},
+	"vinInfo":   {},
+	"source":    {},
+	"fetchedAt": {},
+	"createdAt": {},
+	"updatedAt": {},
+	"updatedBy": {},
+}
+
+// carDataRequest is the request body which contains the car in carNewData field
+type carDataRequest struct {
+	CarNewData json.RawMessage `json:"carNewData"`
+}
+
+// readCarData returns the car document of the request. Merge patch body is the document itself,
+// other bodies contain it in carNewData field
+func readCarData(r *http.Request) (json.RawMessage, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == contentTypeMergePatch {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	req := carDataRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, err
+	}
+	return req.CarNewData, nil
+}
+
+// parseCarPatch decodes the car document and returns all errors of its fields.
+// Missing fields arent changed, null clears the nullable fields and is an error for the other ones,
+// unknown and read only fields are rejected
+func parseCarPatch(data json.RawMessage) (models.CarForPatch, []models.FieldError) {
+	var (
+		newData models.CarForPatch
+		errs    []models.FieldError
+	)
+	if len(data) == 0 || isJsonNull(data) {
+		return newData, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return newData, []models.FieldError{{Rule: models.RuleType, Message: "car data must be an object"}}
+	}
+	for _, name := range sortedKeys(fields) {
+		value := fields[name]
+		var fieldErr *models.FieldError
+		switch name {
+		case "regNum":
+			fieldErr = decodeRequiredField(name, value, &newData.RegisterNumber)
+		case "mark":
+			fieldErr = decodeRequiredField(name, value, &newData.Mark)
+		case "model":
+			fieldErr = decodeRequiredField(name, value, &newData.Model)
+		case "year":
+			fieldErr = decodeRequiredField(name, value, &newData.Year)
+		case "vin":
+			if isJsonNull(value) {
+				newData.ClearVin = true
+				continue
+			}
+			fieldErr = decodeRequiredField(name, value, &newData.Vin)
+		case "owner":
+			var ownerErrs []models.FieldError
+			newData.Owner, ownerErrs = parseOwnerPatch(value)
+			errs = append(errs, ownerErrs...)
+		default:
+			if _, ok := readOnlyCarFields[name]; ok {
+				fieldErr = &models.FieldError{Field: name, Rule: models.RuleReadOnly, Message: fmt.Sprintf("%s cant be changed", name)}
+			} else {
+				fieldErr = &models.FieldError{Field: name, Rule: models.RuleUnknown, Message: fmt.Sprintf("unknown field %s", name)}
+			}
+		}
+		if fieldErr != nil {
+			errs = append(errs, *fieldErr)
+		}
+	}
+	return newData, errs
+}
+
+func parseOwnerPatch(data json.RawMessage) (*models.OwnerForPatch, []models.FieldError) {
+	if isJsonNull(data) {
+		return nil, []models.FieldError{{Field: "owner", Rule: models.RuleNull, Message: "owner cant be null"}}
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, []models.FieldError{{Field: "owner", Rule: models.RuleType, Message: "owner must be an object"}}
+	}
+	var (
+		owner models.OwnerForPatch
+		errs  []models.FieldError
+	)
+	for _, name := range sortedKeys(fields) {
+		value := fields[name]
+		var fieldErr *models.FieldError
+		switch name {
+		case "name":
+			fieldErr = decodeRequiredField("owner.name", value, &owner.Name)
+		case "surname":
+			fieldErr = decodeRequiredField("owner.surname", value, &owner.Surname)
+		case "patronymic":
+			if isJsonNull(value) {
+				owner.ClearPatronymic = true
+				continue
+			}
+			fieldErr = decodeRequiredField("owner.patronymic", value, &owner.Patronymic)
+		default:
+			fieldErr = &models.FieldError{Field: "owner." + name, Rule: models.RuleUnknown, Message: fmt.Sprintf("unknown field owner.%s", name)}
+		}
+		if fieldErr != nil {
+			errs = append(errs, *fieldErr)
+		}
+	}
+	return &owner, errs
+}
+
+// decodeRequiredField decodes not null value into the target and returns error of the field
+func decodeRequiredField(name string, value json.RawMessage, target interface{}) *models.FieldError {
+	if isJsonNull(value) {
+		return &models.FieldError{Field: name, Rule: models.RuleNull, Message: fmt.Sprintf("%s cant be null", name)}
+	}
+	if err := json.Unmarshal(value, target); err != nil {
+		return &models.FieldError{Field: name, Rule: models.RuleType, Message: fmt.Sprintf("not valid type of %s", name)}
+	}
+	return nil
+}
+
+// patchToCar returns the full car from the new data, missing fields are empty
+func patchToCar(newData models.CarForPatch) models.Car {
+	var car models.Car
+	value := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	car.RegisterNumber = value(newData.RegisterNumber)
+	car.Mark = value(newData.Mark)
+	car.Model = value(newData.Model)
+	if newData.Year != nil {
+		car.Year = *newData.Year
+	}
+	car.Vin = newData.Vin
+	if newData.Owner != nil {
+		car.Owner = &models.Owner{
+			Name:       value(newData.Owner.Name),
+			Surname:    value(newData.Owner.Surname),
+			Patronymic: newData.Owner.Patronymic,
+		}
+	}
+	return car
+}
+
+// appendFieldErrors adds errors of the validation, fields which already have errors of the decoding
+// and fields of the failed objects are skipped
+func appendFieldErrors(errs, validationErrs []models.FieldError) []models.FieldError {
+	failed := make(map[string]struct{}, len(errs))
+	for _, fieldErr := range errs {
+		failed[fieldErr.Field] = struct{}{}
+	}
+	for _, fieldErr := range validationErrs {
+		parent, _, _ := strings.Cut(fieldErr.Field, ".")
+		_, fieldFailed := failed[fieldErr.Field]
+		_, parentFailed := failed[parent]
+		if !fieldFailed && !parentFailed {
+			errs = append(errs, fieldErr)
+		}
+	}
+	return errs
+}
+
+func isJsonNull(data json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
+
+// sortedKeys makes the order of the errors stable
+func sortedKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
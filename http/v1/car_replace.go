@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+	"github.com/gorilla/mux"
+)
+
+type carReplacer interface {
+	ReplaceCar(context.Context, string, models.Car) error
+}
+
+type carFullValidator interface {
+	ValidateCar(models.Car) []models.FieldError
+}
+
+// @summary Заменить данные машины
+// @tags Car
+// @description Полная замена данных машины по ее идентификатору
+// @description Все поля, кроме отчества владельца, обязательны, отсутствующее отчество очищается
+// @description Неизвестные поля отклоняются, в ответе возвращаются все ошибки
+// @id Car_replace
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce plain
+// @Param carId path string true "Идентификатор машины"
+// @Param carNewData body models.Car true "Новые данные машины"
+// @Router /api/v2/cars/{carId} [put]
+// @Success 200
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarReplace(logger *slog.Logger, cValidator carFullValidator, cReplacer carReplacer) http.HandlerFunc {
+	handlerName := slog.String("handler", "replace_car")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to replace a car")
+		carId, ok := mux.Vars(r)["carId"]
+		if !ok || carId == "" {
+			log.Warn("empty car id")
+			problem.BadRequest(w, r, "empty car id")
+			return
+		}
+		log.Debug("got car id", slog.String("car_id", carId))
+		data, err := readCarData(r)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, "error while decoding request: "+err.Error())
+			return
+		}
+		newData, errs := parseCarPatch(data)
+		car := patchToCar(newData)
+		errs = appendFieldErrors(errs, cValidator.ValidateCar(car))
+		if len(errs) != 0 {
+			log.Info("validate error", slog.Any("car_new_data", newData), slog.Any("errors", errs))
+			problem.ValidationErrors(w, r, errs)
+			return
+		}
+		log.Debug("got data from request", slog.Any("car_new_data", newData))
+		err = cReplacer.ReplaceCar(r.Context(), carId, car)
+		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
+			log.Warn("failed to replace the car",
+			slog.String("car_id", carId),
+			slog.Any("car_new_data", car),
+			slog.String("error", err.Error()))
+			problem.Error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
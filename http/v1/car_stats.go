@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+)
+
+type carStatsGetter interface {
+	GetCarStats(context.Context, models.Filter, models.StatsOption) (models.CarStats, error)
+}
+
+// query stats parameters name
+const (
+	facetsParamName     = "facets"
+	aggregatesParamName = "aggregates"
+	yearBucketParamName = "year_bucket"
+	facetLimitParamName = "facet_limit"
+
+	maxYearBucket = 100
+)
+
+var facetNames = []string{
+	models.FacetMark,
+	models.FacetModel,
+	models.FacetYear,
+	models.FacetPlateRegion,
+}
+
+var aggregateNames = []string{
+	models.AggregateAvgAge,
+	models.AggregateMinYear,
+	models.AggregateMaxYear,
+}
+
+// @summary Получить статистику машин
+// @tags Car
+// @description Получение количества машин, агрегатов и количества машин по значениям полей (фасетов) с тем же фильтром, что и при получении машин
+// @description
+// @description Фасеты: mark, model (вместе с маркой), year (по интервалам year_bucket лет), plate_region (машины без распознанного номера не учитываются)
+// @description Агрегаты: avg_age (средний возраст), min_year, max_year. Агрегаты считаются для всех машин и для каждого значения фасетов
+// @id Car_stats
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce json
+// @Param facets query string false "Фасеты через запятую" example(mark,year)
+// @Param aggregates query string false "Агрегаты через запятую" example(avg_age)
+// @Param year_bucket query integer false "Размер интервала годов" minimum(1) maximum(100) default(1)
+// @Param facet_limit query integer false "Количество самых частых значений фасета, не больше ограничения из конфига, не применяется к году" minimum(1)
+// @Param mark query []string false "Фильтр для поля марки" example(or:like:Lada) collectionFormat(multi)
+// @Param year query []string false "Фильтр для поля года" example(and:gt:2001) collectionFormat(multi)
+// @Param plate_region query []string false "Фильтр для кода региона номерного знака" example(77) collectionFormat(multi)
+// @Router /api/v2/cars/stats [get]
+// @Success 200 {object} httpmodels.CarStatsResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarStats(logger *slog.Logger, statsCfg config.StatsConfig, statsGetter carStatsGetter, fAdder filterAdder) http.HandlerFunc {
+	handlerName := slog.String("handler", "get_cars_stats")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to get cars stats")
+		option, err := parseStatsQuery(r.URL.Query(), statsCfg)
+		if err != nil {
+			log.Info("wrong stats parameters", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		var filter models.Filter
+		// the wrong filter would count more cars, so it isnt skipped
+		if err = parseFilterQuery(r, log, &filter, fAdder); err != nil {
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		stats, err := statsGetter.GetCarStats(r.Context(), filter, option)
+		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
+			log.Error("failed to get cars stats", slog.String("error", err.Error()))
+			problem.Error(w, r, err)
+			return
+		}
+		log.Debug("got cars stats", slog.Any("stats", stats))
+		res := &httpmodels.CarStatsResponse{
+			Stats: stats,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(resData)
+	}
+}
+
+// parseStatsQuery returns facets and aggregates from the query, the facet limit is capped by the config
+func parseStatsQuery(queries url.Values, statsCfg config.StatsConfig) (models.StatsOption, error) {
+	option := models.StatsOption{
+		YearBucket: 1,
+		Limit:      statsCfg.FacetLimit,
+	}
+	var err error
+	if option.Facets, err = parseNames(queries[facetsParamName], facetNames, "facet"); err != nil {
+		return models.StatsOption{}, err
+	}
+	if option.Aggregates, err = parseNames(queries[aggregatesParamName], aggregateNames, "aggregate"); err != nil {
+		return models.StatsOption{}, err
+	}
+	if value := queries.Get(yearBucketParamName); value != "" {
+		bucket, err := strconv.Atoi(value)
+		if err != nil || bucket < 1 || bucket > maxYearBucket {
+			return models.StatsOption{}, fmt.Errorf("%s must be from 1 to %d", yearBucketParamName, maxYearBucket)
+		}
+		option.YearBucket = bucket
+	}
+	if value := queries.Get(facetLimitParamName); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return models.StatsOption{}, fmt.Errorf("%s must be positive number", facetLimitParamName)
+		}
+		if statsCfg.FacetLimit <= 0 || limit < statsCfg.FacetLimit {
+			option.Limit = limit
+		}
+	}
+	return option, nil
+}
+
+// parseNames returns known names from the comma separated values without repeats
+func parseNames(values []string, known []string, kind string) ([]string, error) {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !containsName(known, name) {
+				return nil, fmt.Errorf("unknown %s %s", kind, name)
+			}
+			if !containsName(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
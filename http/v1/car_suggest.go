@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+)
+
+type carSuggester interface {
+	Suggest(context.Context, models.SuggestQuery) ([]models.Suggestion, error)
+}
+
+// query suggest parameters name
+const (
+	suggestFieldParamName  = "field"
+	suggestPrefixParamName = "prefix"
+	suggestMarkParamName   = "mark"
+	suggestLimitParamName  = "limit"
+
+	defaultSuggestLimit = 10
+	maxSuggestPrefix    = 100
+)
+
+var suggestFieldNames = []string{
+	models.SuggestMark,
+	models.SuggestModel,
+	models.SuggestOwnerName,
+	models.SuggestOwnerSurname,
+}
+
+// @summary Получить подсказки значений поля
+// @tags Car
+// @description Получение самых частых значений поля машин, начинающихся с префикса, регистр не учитывается
+// @description
+// @description Значения обновляются периодически, поэтому последние изменения машин появляются в подсказках с задержкой
+// @id Car_suggest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce json
+// @Param field query string true "Поле (mark, model, owner_name, owner_surname)" example(mark)
+// @Param prefix query string false "Начало значения" example(la)
+// @Param mark query string false "Марка, модели которой подсказываются, только для поля model" example(Lada)
+// @Param limit query integer false "Количество значений, не больше ограничения из конфига" minimum(1)
+// @Router /api/v2/cars/suggest [get]
+// @Success 200 {object} httpmodels.CarSuggestResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CarSuggest(logger *slog.Logger, suggestCfg config.SuggestConfig, suggester carSuggester) http.HandlerFunc {
+	handlerName := slog.String("handler", "suggest_cars")
+	baseLog := logger.With(handlerName)
+	maxLimit := suggestCfg.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = defaultSuggestLimit
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to get suggestions")
+		query, err := parseSuggestQuery(r, maxLimit)
+		if err != nil {
+			log.Info("wrong suggest parameters", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, err.Error())
+			return
+		}
+		suggestions, err := suggester.Suggest(r.Context(), query)
+		if err != nil {
+			if errors.Is(err, service.ErrCanceled) {
+				log.Info("request was canceled", slog.String("error", err.Error()))
+				problem.Error(w, r, err)
+				return
+			}
+			log.Error("failed to get suggestions", slog.String("error", err.Error()))
+			problem.Error(w, r, err)
+			return
+		}
+		log.Debug("got suggestions", slog.Any("suggestions", suggestions))
+		res := &httpmodels.CarSuggestResponse{
+			Suggestions: suggestions,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(resData)
+	}
+}
+
+// parseSuggestQuery returns the field, prefix and limit from the query, the limit is capped by maxLimit
+func parseSuggestQuery(r *http.Request, maxLimit int) (models.SuggestQuery, error) {
+	queries := r.URL.Query()
+	query := models.SuggestQuery{
+		Field:  queries.Get(suggestFieldParamName),
+		Prefix: queries.Get(suggestPrefixParamName),
+		Mark:   queries.Get(suggestMarkParamName),
+		Limit:  maxLimit,
+	}
+	if query.Field == "" {
+		return models.SuggestQuery{}, fmt.Errorf("%s is required", suggestFieldParamName)
+	}
+	if !containsName(suggestFieldNames, query.Field) {
+		return models.SuggestQuery{}, fmt.Errorf("unknown suggested field %s", query.Field)
+	}
+	if query.Mark != "" && query.Field != models.SuggestModel {
+		return models.SuggestQuery{}, fmt.Errorf("%s can be used only for %s field", suggestMarkParamName, models.SuggestModel)
+	}
+	if utf8.RuneCountInString(query.Prefix) > maxSuggestPrefix {
+		return models.SuggestQuery{}, fmt.Errorf("%s must be at most %d characters", suggestPrefixParamName, maxSuggestPrefix)
+	}
+	if value := queries.Get(suggestLimitParamName); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return models.SuggestQuery{}, fmt.Errorf("%s must be positive number", suggestLimitParamName)
+		}
+		if limit < maxLimit {
+			query.Limit = limit
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,396 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+	"github.com/gorilla/mux"
+)
+
+type catalogGetter interface {
+	GetCatalog(context.Context) ([]models.CatalogMark, error)
+}
+
+type catalogReporter interface {
+	GetCatalogReport(context.Context) ([]models.UnmatchedValue, error)
+}
+
+type catalogMarkEditor interface {
+	AddMark(context.Context, models.CatalogMark) (models.CatalogMark, error)
+	EditMark(context.Context, models.CatalogMark) error
+	DeleteMark(context.Context, int) error
+}
+
+type catalogModelEditor interface {
+	AddModel(context.Context, models.CatalogModel) (models.CatalogModel, error)
+	EditModel(context.Context, models.CatalogModel) error
+	DeleteModel(context.Context, int) error
+}
+
+// @summary Получить справочник марок и моделей
+// @tags Catalog
+// @description Получение всех марок справочника с их моделями и синонимами
+// @id Catalog_get
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce json
+// @Router /api/v2/catalog/marks [get]
+// @Success 200 {object} httpmodels.CatalogGetResponse
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogGet(logger *slog.Logger, cGetter catalogGetter) http.HandlerFunc {
+	handlerName := slog.String("handler", "get_catalog")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to get catalog")
+		marks, err := cGetter.GetCatalog(r.Context())
+		if err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		writeCatalogResponse(w, r, log, http.StatusOK, &httpmodels.CatalogGetResponse{Marks: marks})
+	}
+}
+
+// @summary Получить отчет о несовпадениях со справочником
+// @tags Catalog
+// @description Марки и модели сохраненных машин, которых нет в справочнике, с количеством машин
+// @description Модель считается несовпавшей, только если ее марка есть в справочнике
+// @id Catalog_report
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce json
+// @Router /api/v2/catalog/report [get]
+// @Success 200 {object} httpmodels.CatalogReportResponse
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogReport(logger *slog.Logger, cReporter catalogReporter) http.HandlerFunc {
+	handlerName := slog.String("handler", "catalog_report")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to get catalog report")
+		unmatched, err := cReporter.GetCatalogReport(r.Context())
+		if err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		writeCatalogResponse(w, r, log, http.StatusOK, &httpmodels.CatalogReportResponse{Unmatched: unmatched})
+	}
+}
+
+// @summary Добавить марку в справочник
+// @tags Catalog
+// @description Добавление марки с синонимами, имя и синонимы не должны совпадать с другими марками без учета регистра и пробелов
+// @id Catalog_mark_add
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce json
+// @Param mark body httpmodels.CatalogEntryRequest true "Марка"
+// @Router /api/v2/catalog/marks [post]
+// @Success 201 {object} httpmodels.CatalogMarkResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogMarkAdd(logger *slog.Logger, mEditor catalogMarkEditor) http.HandlerFunc {
+	handlerName := slog.String("handler", "add_catalog_mark")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to add catalog mark")
+		req, ok := readCatalogEntry(w, r, log)
+		if !ok {
+			return
+		}
+		mark, err := mEditor.AddMark(r.Context(), models.CatalogMark{Name: req.Name, Aliases: req.Aliases})
+		if err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		writeCatalogResponse(w, r, log, http.StatusCreated, &httpmodels.CatalogMarkResponse{Mark: mark})
+	}
+}
+
+// @summary Изменить марку справочника
+// @tags Catalog
+// @description Замена имени и синонимов марки, модели марки не меняются
+// @id Catalog_mark_edit
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce plain
+// @Param markId path int true "Идентификатор марки"
+// @Param mark body httpmodels.CatalogEntryRequest true "Марка"
+// @Router /api/v2/catalog/marks/{markId} [put]
+// @Success 200
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogMarkEdit(logger *slog.Logger, mEditor catalogMarkEditor) http.HandlerFunc {
+	handlerName := slog.String("handler", "edit_catalog_mark")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to edit catalog mark")
+		markId, ok := catalogId(w, r, log, "markId")
+		if !ok {
+			return
+		}
+		req, ok := readCatalogEntry(w, r, log)
+		if !ok {
+			return
+		}
+		err := mEditor.EditMark(r.Context(), models.CatalogMark{Id: markId, Name: req.Name, Aliases: req.Aliases})
+		if err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// @summary Удалить марку из справочника
+// @tags Catalog
+// @description Удаление марки вместе с ее моделями, данные машин не меняются
+// @id Catalog_mark_delete
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce plain
+// @Param markId path int true "Идентификатор марки"
+// @Router /api/v2/catalog/marks/{markId} [delete]
+// @Success 200
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogMarkDelete(logger *slog.Logger, mEditor catalogMarkEditor) http.HandlerFunc {
+	handlerName := slog.String("handler", "delete_catalog_mark")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to delete catalog mark")
+		markId, ok := catalogId(w, r, log, "markId")
+		if !ok {
+			return
+		}
+		if err := mEditor.DeleteMark(r.Context(), markId); err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// @summary Добавить модель марки в справочник
+// @tags Catalog
+// @description Добавление модели с синонимами, имя и синонимы не должны совпадать с другими моделями марки
+// @id Catalog_model_add
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce json
+// @Param markId path int true "Идентификатор марки"
+// @Param model body httpmodels.CatalogEntryRequest true "Модель"
+// @Router /api/v2/catalog/marks/{markId}/models [post]
+// @Success 201 {object} httpmodels.CatalogModelResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogModelAdd(logger *slog.Logger, mEditor catalogModelEditor) http.HandlerFunc {
+	handlerName := slog.String("handler", "add_catalog_model")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to add catalog model")
+		markId, ok := catalogId(w, r, log, "markId")
+		if !ok {
+			return
+		}
+		req, ok := readCatalogEntry(w, r, log)
+		if !ok {
+			return
+		}
+		model, err := mEditor.AddModel(r.Context(), models.CatalogModel{MarkId: markId, Name: req.Name, Aliases: req.Aliases})
+		if err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		writeCatalogResponse(w, r, log, http.StatusCreated, &httpmodels.CatalogModelResponse{Model: model})
+	}
+}
+
+// @summary Изменить модель справочника
+// @tags Catalog
+// @description Замена имени и синонимов модели, марка модели не меняется
+// @id Catalog_model_edit
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @accept json
+// @produce plain
+// @Param modelId path int true "Идентификатор модели"
+// @Param model body httpmodels.CatalogEntryRequest true "Модель"
+// @Router /api/v2/catalog/models/{modelId} [put]
+// @Success 200
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogModelEdit(logger *slog.Logger, mEditor catalogModelEditor) http.HandlerFunc {
+	handlerName := slog.String("handler", "edit_catalog_model")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to edit catalog model")
+		modelId, ok := catalogId(w, r, log, "modelId")
+		if !ok {
+			return
+		}
+		req, ok := readCatalogEntry(w, r, log)
+		if !ok {
+			return
+		}
+		err := mEditor.EditModel(r.Context(), models.CatalogModel{Id: modelId, Name: req.Name, Aliases: req.Aliases})
+		if err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// @summary Удалить модель из справочника
+// @tags Catalog
+// @description Удаление модели, данные машин не меняются
+// @id Catalog_model_delete
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @produce plain
+// @Param modelId path int true "Идентификатор модели"
+// @Router /api/v2/catalog/models/{modelId} [delete]
+// @Success 200
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+//
+func CatalogModelDelete(logger *slog.Logger, mEditor catalogModelEditor) http.HandlerFunc {
+	handlerName := slog.String("handler", "delete_catalog_model")
+	baseLog := logger.With(handlerName)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := l.FromContext(r.Context(), baseLog, handlerName)
+		log.Info("attempt to delete catalog model")
+		modelId, ok := catalogId(w, r, log, "modelId")
+		if !ok {
+			return
+		}
+		if err := mEditor.DeleteModel(r.Context(), modelId); err != nil {
+			writeCatalogError(w, r, log, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// catalogId returns id from the path, the problem is written if it isnt a number
+func catalogId(w http.ResponseWriter, r *http.Request, log *slog.Logger, name string) (int, bool) {
+	value := mux.Vars(r)[name]
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		log.Info("not valid catalog id", slog.String(name, value))
+		problem.BadRequest(w, r, fmt.Sprintf("not valid %s", name))
+		return 0, false
+	}
+	return id, true
+}
+
+// readCatalogEntry decodes and checks the mark or model, the problem is written if the request isnt valid
+func readCatalogEntry(w http.ResponseWriter, r *http.Request, log *slog.Logger) (httpmodels.CatalogEntryRequest, bool) {
+	req := httpmodels.CatalogEntryRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		log.Error("failed to decode request body", slog.String("error", err.Error()))
+		problem.BadRequest(w, r, "error while decoding request: "+err.Error())
+		return req, false
+	}
+	log.Debug("got data from request", slog.Any("request_body", req))
+	var errs []models.FieldError
+	if strings.TrimSpace(req.Name) == "" {
+		errs = append(errs, models.FieldError{Field: "name", Rule: models.RuleRequired, Message: "name is required"})
+	}
+	for i, alias := range req.Aliases {
+		if strings.TrimSpace(alias) == "" {
+			field := fmt.Sprintf("aliases[%d]", i)
+			errs = append(errs, models.FieldError{Field: field, Rule: models.RuleRequired, Message: field + " cant be empty"})
+		}
+	}
+	if len(errs) != 0 {
+		log.Info("validate error", slog.Any("request_body", req), slog.Any("errors", errs))
+		problem.ValidationErrors(w, r, errs)
+		return req, false
+	}
+	if req.Aliases == nil {
+		req.Aliases = []string{}
+	}
+	return req, true
+}
+
+func writeCatalogError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	if errors.Is(err, service.ErrCanceled) {
+		log.Info("request was canceled", slog.String("error", err.Error()))
+		problem.Error(w, r, err)
+		return
+	}
+	log.Warn("failed to process catalog", slog.String("error", err.Error()))
+	problem.Error(w, r, err)
+}
+
+func writeCatalogResponse(w http.ResponseWriter, r *http.Request, log *slog.Logger, status int, res interface{}) {
+	resData, err := json.Marshal(res)
+	if err != nil {
+		log.Error("cant encode response", slog.String("error", err.Error()))
+		problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "error while encoding response")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resData)
+}
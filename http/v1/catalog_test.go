@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/httpmodels"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/service"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+type memoryMarkEditor struct {
+	added  []models.CatalogMark
+	edited []models.CatalogMark
+	err    error
+}
+
+func (m *memoryMarkEditor) AddMark(_ context.Context, mark models.CatalogMark) (models.CatalogMark, error) {
+	if m.err != nil {
+		return models.CatalogMark{}, m.err
+	}
+	m.added = append(m.added, mark)
+	mark.Id = 1
+	mark.Models = []models.CatalogModel{}
+	return mark, nil
+}
+
+func (m *memoryMarkEditor) EditMark(_ context.Context, mark models.CatalogMark) error {
+	m.edited = append(m.edited, mark)
+	return m.err
+}
+
+func (m *memoryMarkEditor) DeleteMark(context.Context, int) error {
+	return m.err
+}
+
+func TestCatalogMarkAdd(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantAdded  []models.CatalogMark
+	}{
+		{
+			name:       "mark",
+			body:       `{"name":"Lada","aliases":["ВАЗ"]}`,
+			wantStatus: http.StatusCreated,
+			wantAdded:  []models.CatalogMark{{Name: "Lada", Aliases: []string{"ВАЗ"}}},
+		},
+		{
+			name:       "without aliases",
+			body:       `{"name":"Lada"}`,
+			wantStatus: http.StatusCreated,
+			wantAdded:  []models.CatalogMark{{Name: "Lada", Aliases: []string{}}},
+		},
+		{name: "unknown field", body: `{"name":"Lada","models":[]}`, wantStatus: http.StatusBadRequest},
+		{name: "empty name", body: `{"name":"  "}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "empty alias", body: `{"name":"Lada","aliases":[""]}`, wantStatus: http.StatusUnprocessableEntity},
+		{
+			name:       "conflict",
+			body:       `{"name":"Lada"}`,
+			err:        fmt.Errorf("%w: %w", service.ErrEditCatalog, service.ErrCatalogConflict),
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "name is taken concurrently",
+			body:       `{"name":"Lada"}`,
+			err:        fmt.Errorf("%w: %w", service.ErrEditCatalog, storage.ErrCatalogExist),
+			wantStatus: http.StatusConflict,
+		},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := &memoryMarkEditor{err: tt.err}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/api/v2/catalog/marks", strings.NewReader(tt.body))
+			CatalogMarkAdd(log, editor).ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !reflect.DeepEqual(editor.added, tt.wantAdded) {
+				t.Errorf("added marks = %+v, want %+v", editor.added, tt.wantAdded)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var res httpmodels.CatalogMarkResponse
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if res.Mark.Id != 1 || res.Mark.Name != "Lada" {
+				t.Errorf("response mark = %+v", res.Mark)
+			}
+		})
+	}
+}
+
+func TestCatalogMarkEdit(t *testing.T) {
+	tests := []struct {
+		name       string
+		markId     string
+		err        error
+		wantStatus int
+	}{
+		{name: "mark", markId: "3", wantStatus: http.StatusOK},
+		{name: "not valid id", markId: "lada", wantStatus: http.StatusBadRequest},
+		{
+			name:       "not found",
+			markId:     "3",
+			err:        fmt.Errorf("%w: %w", service.ErrEditCatalog, storage.ErrCatalogNotFound),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := &memoryMarkEditor{err: tt.err}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("PUT", "/api/v2/catalog/marks/"+tt.markId, strings.NewReader(`{"name":"Lada"}`))
+			r = mux.SetURLVars(r, map[string]string{"markId": tt.markId})
+			CatalogMarkEdit(log, editor).ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if len(editor.edited) != 0 {
+					t.Errorf("mark is edited with not valid id")
+				}
+				return
+			}
+			if len(editor.edited) != 1 || editor.edited[0].Id != 3 {
+				t.Errorf("edited marks = %+v", editor.edited)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package auth
+
+import "context"
+
+// scopes of the api
+const (
+	ScopeCarsRead   = "cars:read"
+	ScopeCarsWrite  = "cars:write"
+	ScopeCarsDelete = "cars:delete"
+	// changes of the marks and models catalog
+	ScopeCatalogWrite = "catalog:write"
+)
+
+// Principal is the authenticated client
+type Principal struct {
+	Name   string
+	Role   string
+	Scopes []string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, principal)
+}
+
+// FromContext returns principal of the request, ok is false for anonymous requests
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ctxKey{}).(Principal)
+	return principal, ok
+}
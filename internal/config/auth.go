@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AuthConfig struct {
+	Enabled     bool           `yaml:"enabled"`
+	ApiKeys     []ApiKeyConfig `yaml:"api_keys"`
+	JwtSecret   string         `yaml:"jwt_secret"`
+	JwtIssuer   string         `yaml:"jwt_issuer"`
+	JwtAudience string         `yaml:"jwt_audience"`
+	Access      AccessConfig   `yaml:"access"`
+}
+
+type ApiKeyConfig struct {
+	Name   string   `yaml:"name"`
+	Key    string   `yaml:"key"`
+	Role   string   `yaml:"role"`
+	Scopes []string `yaml:"scopes"`
+}
+
+// access modes of the owner personal data
+const (
+	OwnerAccessFull = "full"
+	OwnerAccessMask = "mask"
+	OwnerAccessOmit = "omit"
+)
+
+// AccessConfig describes which data is available to the roles
+type AccessConfig struct {
+	// role of the principals without role and of the anonymous requests,
+	// empty value means full access
+	DefaultRole string                `yaml:"default_role"`
+	Roles       map[string]RoleConfig `yaml:"roles"`
+}
+
+type RoleConfig struct {
+	Owner string `yaml:"owner"`
+}
+
+func (a *AccessConfig) checkRoles() error {
+	for name, role := range a.Roles {
+		switch role.Owner {
+		case OwnerAccessFull, OwnerAccessMask, OwnerAccessOmit:
+		default:
+			return fmt.Errorf("incorrect owner access of role %s", name)
+		}
+	}
+	if _, ok := a.Roles[a.DefaultRole]; a.DefaultRole != "" && !ok {
+		return fmt.Errorf("unknown default role %s", a.DefaultRole)
+	}
+	return nil
+}
+
+// minimal lengths of the secrets, the shorter ones can be guessed
+const (
+	minJwtSecretLength = 32
+	minApiKeyLength    = 16
+)
+
+// placeholderSecrets are the example values which must be replaced before the start
+var placeholderSecrets = map[string]struct{}{
+	"change-me":         {},
+	"changeme":          {},
+	"secret":            {},
+	"password":          {},
+	"admin-secret-key":  {},
+	"reader-secret-key": {},
+}
+
+// checkSecret returns error if the secret is the placeholder or it is too short
+func checkSecret(secret string, minLength int) error {
+	if _, ok := placeholderSecrets[strings.ToLower(secret)]; ok {
+		return fmt.Errorf("placeholder value is used")
+	}
+	if len(secret) < minLength {
+		return fmt.Errorf("it must be at least %d bytes", minLength)
+	}
+	return nil
+}
+
+func (a *AuthConfig) checkKeys() error {
+	if err := a.Access.checkRoles(); err != nil {
+		return err
+	}
+	if !a.Enabled {
+		return nil
+	}
+	if len(a.ApiKeys) == 0 && a.JwtSecret == "" {
+		return fmt.Errorf("auth is enabled, but there are no api keys and jwt secret")
+	}
+	if a.JwtSecret != "" {
+		if err := checkSecret(a.JwtSecret, minJwtSecretLength); err != nil {
+			return fmt.Errorf("incorrect jwt secret: %w", err)
+		}
+	}
+	names := make(map[string]struct{}, len(a.ApiKeys))
+	keys := make(map[string]struct{}, len(a.ApiKeys))
+	for _, apiKey := range a.ApiKeys {
+		if apiKey.Name == "" || apiKey.Key == "" {
+			return fmt.Errorf("api key must have name and key")
+		}
+		if err := checkSecret(apiKey.Key, minApiKeyLength); err != nil {
+			return fmt.Errorf("incorrect api key of %s: %w", apiKey.Name, err)
+		}
+		if _, ok := names[apiKey.Name]; ok {
+			return fmt.Errorf("duplicated api key name %s", apiKey.Name)
+		}
+		if _, ok := a.Access.Roles[apiKey.Role]; apiKey.Role != "" && !ok {
+			return fmt.Errorf("unknown role of %s", apiKey.Name)
+		}
+		if _, ok := keys[apiKey.Key]; ok {
+			return fmt.Errorf("duplicated api key of %s", apiKey.Name)
+		}
+		names[apiKey.Name] = struct{}{}
+		keys[apiKey.Key] = struct{}{}
+	}
+	return nil
+}
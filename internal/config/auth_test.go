@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestCheckKeys(t *testing.T) {
+	validKey := ApiKeyConfig{Name: "admin", Key: "0123456789abcdef0123"}
+	tests := []struct {
+		name    string
+		cfg     AuthConfig
+		wantErr bool
+	}{
+		{name: "disabled without secrets", cfg: AuthConfig{}},
+		{name: "enabled without secrets", cfg: AuthConfig{Enabled: true}, wantErr: true},
+		{name: "valid api key", cfg: AuthConfig{Enabled: true, ApiKeys: []ApiKeyConfig{validKey}}},
+		{name: "placeholder api key", cfg: AuthConfig{Enabled: true, ApiKeys: []ApiKeyConfig{{Name: "admin", Key: "admin-secret-key"}}}, wantErr: true},
+		{name: "short api key", cfg: AuthConfig{Enabled: true, ApiKeys: []ApiKeyConfig{{Name: "admin", Key: "short"}}}, wantErr: true},
+		{name: "placeholder jwt secret", cfg: AuthConfig{Enabled: true, JwtSecret: "change-me"}, wantErr: true},
+		{name: "short jwt secret", cfg: AuthConfig{Enabled: true, JwtSecret: "0123456789"}, wantErr: true},
+		{name: "valid jwt secret", cfg: AuthConfig{Enabled: true, JwtSecret: "0123456789abcdef0123456789abcdef"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.checkKeys(); (err != nil) != tt.wantErr {
+				t.Errorf("checkKeys() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
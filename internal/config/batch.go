@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// BatchConfig contains settings of the batch edit and delete
+type BatchConfig struct {
+	// max number of the cars changed by one request, zero value means no limit
+	MaxCars int `yaml:"max_cars"`
+	// deletes by filter are disabled without the secret of their confirm tokens
+	DeleteByFilter bool `yaml:"delete_by_filter"`
+	// secret of the confirm tokens, the same for all instances, so the token of one is accepted by the other
+	ConfirmSecret string `yaml:"confirm_secret"`
+	// lifetime of the confirm token, zero value means 5 minutes
+	ConfirmTtl time.Duration `yaml:"confirm_ttl"`
+}
+
+func (b *BatchConfig) checkConfirm() error {
+	if !b.DeleteByFilter {
+		return nil
+	}
+	if b.ConfirmSecret == "" {
+		return fmt.Errorf("delete by filter is enabled, but there is no confirm secret")
+	}
+	if err := checkSecret(b.ConfirmSecret, minJwtSecretLength); err != nil {
+		return fmt.Errorf("incorrect confirm secret: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestCheckConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BatchConfig
+		wantErr bool
+	}{
+		{name: "disabled without secret", cfg: BatchConfig{}},
+		{name: "enabled without secret", cfg: BatchConfig{DeleteByFilter: true}, wantErr: true},
+		{name: "enabled with placeholder", cfg: BatchConfig{DeleteByFilter: true, ConfirmSecret: "change-me"}, wantErr: true},
+		{name: "enabled with short secret", cfg: BatchConfig{DeleteByFilter: true, ConfirmSecret: "0123456789"}, wantErr: true},
+		{name: "enabled with secret", cfg: BatchConfig{DeleteByFilter: true, ConfirmSecret: "0123456789abcdef0123456789abcdef"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.checkConfirm(); (err != nil) != tt.wantErr {
+				t.Errorf("checkConfirm() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// CatalogConfig contains settings of the marks and models catalog
+type CatalogConfig struct {
+	// period of the catalog reload, changes of the other instances are seen after it, zero disables the reload
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
@@ -11,7 +11,11 @@ type Config struct {
 	CarInfoGetterUrl string          `yaml:"car_info_getter"`
 	HttpConfig       HttpConfig      `yaml:"http"`
 	ValidatorConfig  ValidatorConfig `yaml:"validator"`
+	CatalogConfig    CatalogConfig   `yaml:"catalog"`
+	SuggestConfig    SuggestConfig   `yaml:"suggest"`
 	PostgresConfig   PostgresConfig  `yaml:"postgres"`
+	AuthConfig       AuthConfig      `yaml:"auth"`
+	TracingConfig    TracingConfig   `yaml:"tracing"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -32,7 +36,39 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = cfg.ValidatorConfig.checkRegex()
+	err = cfg.ValidatorConfig.checkRules()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.HttpConfig.LegacyRoutes.checkDates()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.HttpConfig.RateLimit.checkLimits()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.HttpConfig.checkServerTimeouts()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.HttpConfig.Batch.checkConfirm()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.HttpConfig.Export.checkDelimiter()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.AuthConfig.checkKeys()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.TracingConfig.checkExporter()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.SuggestConfig.checkRefresh()
 	if err != nil {
 		return nil, err
 	}
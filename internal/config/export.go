@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// ExportConfig contains settings of the cars export
+type ExportConfig struct {
+	// json names of the exported fields if the request doesnt contain fields parameter, empty means all fields
+	Columns []string `yaml:"columns"`
+	// titles of the csv columns by their names, names are used if title isnt set
+	CsvHeaders   map[string]string `yaml:"csv_headers"`
+	CsvDelimiter string            `yaml:"csv_delimiter"`
+	// number of the rows after which the response is flushed to the client
+	FlushRows int `yaml:"flush_rows"`
+	// prefix the csv values which start with the formula symbols by the quote, so spreadsheets dont evaluate them
+	CsvEscapeFormulas bool `yaml:"csv_escape_formulas"`
+}
+
+func (e *ExportConfig) checkDelimiter() error {
+	if e.CsvDelimiter != "" && utf8.RuneCountInString(e.CsvDelimiter) != 1 {
+		return fmt.Errorf("csv delimiter must be one symbol")
+	}
+	if e.FlushRows < 0 {
+		return fmt.Errorf("negative flush rows")
+	}
+	return nil
+}
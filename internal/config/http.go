@@ -1,6 +1,118 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 type HttpConfig struct {
-	Host        string        `yaml:"host"`
-	Port        string        `yaml:"port"`
-}
\ No newline at end of file
+	Host            string             `yaml:"host"`
+	Port            string             `yaml:"port"`
+	ShutdownTimeout time.Duration      `yaml:"shutdown_timeout"`
+	ReadTimeout     time.Duration      `yaml:"read_timeout"`
+	WriteTimeout    time.Duration      `yaml:"write_timeout"`
+	Timeouts        TimeoutsConfig     `yaml:"timeouts"`
+	RateLimit       RateLimitConfig    `yaml:"rate_limit"`
+	LegacyRoutes    LegacyRoutesConfig `yaml:"legacy_routes"`
+	Health          HealthConfig       `yaml:"health"`
+	Idempotency     IdempotencyConfig  `yaml:"idempotency"`
+	CacheControl    CacheControlConfig `yaml:"cache_control"`
+	Export          ExportConfig       `yaml:"export"`
+	Import          ImportConfig       `yaml:"import"`
+	Batch           BatchConfig        `yaml:"batch"`
+	Stats           StatsConfig        `yaml:"stats"`
+}
+
+// CacheControlConfig contains Cache-Control policies of the read routes, empty value means no header
+type CacheControlConfig struct {
+	GetOne string `yaml:"get_one"`
+	GetAll string `yaml:"get_all"`
+}
+
+// HealthConfig contains settings of the readiness checks
+type HealthConfig struct {
+	Timeout       time.Duration `yaml:"timeout"`
+	CheckUpstream bool          `yaml:"check_upstream"`
+	// time between the failing readiness and the stop of the server, so the balancer stops sending requests
+	Drain time.Duration `yaml:"drain"`
+}
+
+// used if the connection timeouts and the route timeouts arent configured
+const defaultServerTimeout = 15 * time.Second
+
+// time after the deadline of the route in which the problem response is written
+const writeTimeoutMargin = 5 * time.Second
+
+// ServerReadTimeout returns the timeout of reading the request
+func (h *HttpConfig) ServerReadTimeout() time.Duration {
+	if h.ReadTimeout > 0 {
+		return h.ReadTimeout
+	}
+	return defaultServerTimeout
+}
+
+// ServerWriteTimeout returns the timeout of writing the response, by default it is longer than the deadlines
+// of the routes, so their problem response isnt cut. Import and export remove it themselves
+func (h *HttpConfig) ServerWriteTimeout() time.Duration {
+	if h.WriteTimeout > 0 {
+		return h.WriteTimeout
+	}
+	if longest := h.Timeouts.longest(); longest > 0 {
+		return longest + writeTimeoutMargin
+	}
+	return defaultServerTimeout
+}
+
+func (h *HttpConfig) checkServerTimeouts() error {
+	if h.ReadTimeout < 0 || h.WriteTimeout < 0 {
+		return fmt.Errorf("negative server timeout")
+	}
+	if h.WriteTimeout > 0 && h.WriteTimeout <= h.Timeouts.longest() {
+		return fmt.Errorf("write timeout must be longer than the timeouts of the routes except import and export")
+	}
+	return nil
+}
+
+// TimeoutsConfig contains deadlines of the operations, zero value means no deadline
+type TimeoutsConfig struct {
+	Add    time.Duration `yaml:"add"`
+	GetOne time.Duration `yaml:"get_one"`
+	GetAll time.Duration `yaml:"get_all"`
+	Edit   time.Duration `yaml:"edit"`
+	Delete time.Duration `yaml:"delete"`
+	Export time.Duration `yaml:"export"`
+	Import time.Duration `yaml:"import"`
+	Batch  time.Duration `yaml:"batch"`
+}
+
+// longest returns the longest deadline of the routes whose response isnt streamed
+func (t *TimeoutsConfig) longest() time.Duration {
+	var longest time.Duration
+	for _, timeout := range []time.Duration{t.Add, t.GetOne, t.GetAll, t.Edit, t.Delete, t.Batch} {
+		if timeout > longest {
+			longest = timeout
+		}
+	}
+	return longest
+}
+
+// LegacyRoutesConfig contains dates in format 2006-01-02
+// which are sent in headers of the deprecated routes
+type LegacyRoutesConfig struct {
+	DeprecatedAt string `yaml:"deprecated_at"`
+	Sunset       string `yaml:"sunset"`
+}
+
+func (l *LegacyRoutesConfig) checkDates() error {
+	if l.DeprecatedAt != "" {
+		if _, err := time.Parse(time.DateOnly, l.DeprecatedAt); err != nil {
+			return fmt.Errorf("incorrect deprecated_at")
+		}
+	}
+	if l.Sunset != "" {
+		if _, err := time.Parse(time.DateOnly, l.Sunset); err != nil {
+			return fmt.Errorf("incorrect sunset")
+		}
+	}
+	return nil
+}
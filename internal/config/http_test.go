@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerWriteTimeout(t *testing.T) {
+	timeouts := TimeoutsConfig{
+		Add:    30 * time.Second,
+		GetAll: 10 * time.Second,
+		Export: 5 * time.Minute,
+		Import: 5 * time.Minute,
+	}
+	tests := []struct {
+		name    string
+		cfg     HttpConfig
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "derived from routes", cfg: HttpConfig{Timeouts: timeouts}, want: 35 * time.Second},
+		{name: "without route timeouts", cfg: HttpConfig{}, want: defaultServerTimeout},
+		{name: "configured", cfg: HttpConfig{Timeouts: timeouts, WriteTimeout: time.Minute}, want: time.Minute},
+		{name: "shorter than route", cfg: HttpConfig{Timeouts: timeouts, WriteTimeout: 15 * time.Second}, wantErr: true},
+		{name: "negative", cfg: HttpConfig{ReadTimeout: -time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.checkServerTimeouts()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkServerTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := tt.cfg.ServerWriteTimeout(); got != tt.want {
+					t.Errorf("ServerWriteTimeout() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// IdempotencyConfig contains settings of the Idempotency-Key header handling
+type IdempotencyConfig struct {
+	// time while the response is replayed, zero value disables the header handling
+	Window time.Duration `yaml:"window"`
+	// max size of the request body which is hashed, zero means 1 MiB
+	MaxBodySize int64 `yaml:"max_body_size"`
+}
@@ -0,0 +1,7 @@
+package config
+
+// ImportConfig contains settings of the cars import
+type ImportConfig struct {
+	// max size of the uploaded file in bytes, zero value means no limit
+	MaxBodySize int64 `yaml:"max_body_size"`
+}
@@ -1,11 +1,21 @@
 package config
 
 type PostgresConfig struct {
-	ConectionFormat string `yaml:"db_con_format"`
-	Host            string `yaml:"db_host"`
-	Port            string `yaml:"db_port"`
-	User            string `yaml:"db_user"`
-	Password        string `yaml:"db_pass"`
-	Database        string `yaml:"db_name"`
-	CarTable        string `yaml:"db_tbl_car"`
+	ConectionFormat  string `yaml:"db_con_format"`
+	Host             string `yaml:"db_host"`
+	Port             string `yaml:"db_port"`
+	User             string `yaml:"db_user"`
+	Password         string `yaml:"db_pass"`
+	Database         string `yaml:"db_name"`
+	CarTable         string `yaml:"db_tbl_car"`
+	IdempotencyTable string `yaml:"db_tbl_idempotency"`
+	// tables of the marks and models catalog
+	MarkTable        string `yaml:"db_tbl_mark"`
+	ModelTable       string `yaml:"db_tbl_model"`
+	// materialized view of the suggested values
+	SuggestView      string `yaml:"db_view_suggest"`
+	// maximum size of the connection pool, zero means default size
+	MaxConns         int    `yaml:"db_max_conns"`
+	// number of the saved cars from which COPY is used, zero means default number
+	CopyThreshold    int    `yaml:"db_copy_threshold"`
 }
\ No newline at end of file
@@ -0,0 +1,41 @@
+package config
+
+import "fmt"
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// use the first address from X-Forwarded-For header as client ip,
+	// must be enabled only behind the trusted proxy
+	TrustForwardedFor bool                   `yaml:"trust_forwarded_for"`
+	Default           LimitConfig            `yaml:"default"`
+	Routes            map[string]LimitConfig `yaml:"routes"`
+}
+
+// LimitConfig describes token bucket, zero rate means no limit
+type LimitConfig struct {
+	// tokens per second
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+func (r *RateLimitConfig) checkLimits() error {
+	if err := r.Default.check(); err != nil {
+		return fmt.Errorf("incorrect default limit: %w", err)
+	}
+	for route, limit := range r.Routes {
+		if err := limit.check(); err != nil {
+			return fmt.Errorf("incorrect limit of route %s: %w", route, err)
+		}
+	}
+	return nil
+}
+
+func (l LimitConfig) check() error {
+	if l.Rate < 0 {
+		return fmt.Errorf("negative rate")
+	}
+	if l.Rate > 0 && l.Burst < 1 {
+		return fmt.Errorf("burst must be positive")
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -93,11 +95,56 @@ func getEnv(envKey string) string {
 func setValue(r reflect.Value, value string) error {
 	switch r.Kind() {
 	case reflect.Int64:
+		if value == "" {
+			return nil
+		}
+		// sizes are int64 too, only durations have units
+		if r.Type() != reflect.TypeOf(time.Duration(0)) {
+			i, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return err
+			}
+			r.SetInt(i)
+			return nil
+		}
 		dur, err := time.ParseDuration(value)
 		if err != nil {
 			return err
 		}
 		r.SetInt(reflect.ValueOf(dur).Int())
+	case reflect.Int:
+		if value == "" {
+			return nil
+		}
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		r.SetInt(int64(i))
+	case reflect.Float64:
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		r.SetFloat(f)
+	case reflect.Bool:
+		if value == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		r.SetBool(b)
+	// complex values are passed as json
+	case reflect.Slice, reflect.Map:
+		if value == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(value), r.Addr().Interface())
 	default:
 		r.Set(reflect.ValueOf(value))
 	}
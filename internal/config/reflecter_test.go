@@ -0,0 +1,27 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetValueInt64(t *testing.T) {
+	var cfg struct {
+		Timeout time.Duration
+		Size    int64
+	}
+	v := reflect.ValueOf(&cfg).Elem()
+	if err := setValue(v.Field(0), "5m"); err != nil {
+		t.Fatalf("setValue(duration) error = %v", err)
+	}
+	if err := setValue(v.Field(1), "33554432"); err != nil {
+		t.Fatalf("setValue(size) error = %v", err)
+	}
+	if cfg.Timeout != 5*time.Minute || cfg.Size != 33554432 {
+		t.Errorf("got %v and %d, want 5m and 33554432", cfg.Timeout, cfg.Size)
+	}
+	if err := setValue(v.Field(1), "32MiB"); err == nil {
+		t.Errorf("setValue(size) accepted value with unit")
+	}
+}
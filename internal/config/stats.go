@@ -0,0 +1,7 @@
+package config
+
+// StatsConfig contains settings of the cars stats and facets
+type StatsConfig struct {
+	// default and max number of the values of one facet, zero value means no limit
+	FacetLimit int `yaml:"facet_limit"`
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SuggestConfig contains settings of the autocomplete suggestions
+type SuggestConfig struct {
+	// period of the suggestions view refresh, new values are suggested after it
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// lifetime of the cached suggestions, zero disables the cache
+	CacheTtl time.Duration `yaml:"cache_ttl"`
+	// default and max number of the suggested values, zero means 10
+	MaxLimit int `yaml:"max_limit"`
+}
+
+// checkRefresh forbids the suggestions which are never refreshed
+func (s *SuggestConfig) checkRefresh() error {
+	if s.RefreshInterval <= 0 {
+		return fmt.Errorf("suggest refresh interval must be positive")
+	}
+	if s.CacheTtl < 0 {
+		return fmt.Errorf("negative suggest cache ttl")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import "fmt"
+
+const (
+	TracingExporterNone   = ""
+	TracingExporterOtlp   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+type TracingConfig struct {
+	// empty value disables tracing
+	Exporter string `yaml:"exporter"`
+	// host:port of the otlp http receiver
+	Endpoint    string `yaml:"endpoint"`
+	Insecure    bool   `yaml:"insecure"`
+	ServiceName string `yaml:"service_name"`
+	// part of the sampled traces, zero value means all traces
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+func (t *TracingConfig) checkExporter() error {
+	switch t.Exporter {
+	case TracingExporterNone, TracingExporterOtlp, TracingExporterStdout:
+	default:
+		return fmt.Errorf("unknown tracing exporter %s", t.Exporter)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+	return nil
+}
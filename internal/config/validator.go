@@ -1,11 +1,22 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"regexp"
 )
 
+// car fields which can be validated, json paths are used
+var ValidatedCarFields = []string{
+	"regNum",
+	"mark",
+	"model",
+	"year",
+	"owner.name",
+	"owner.surname",
+	"owner.patronymic",
+	"vin",
+}
+
 type ValidatorConfig struct {
 	RegisterNumberRegex  string `yaml:"reg_num"`
 	MarkRegex            string `yaml:"mark"`
@@ -13,17 +24,82 @@ type ValidatorConfig struct {
 	OwnerNameRegex       string `yaml:"owner_name"`
 	OwnerSurnameRegex    string `yaml:"owner_surname"`
 	OwnerPatronymicRegex string `yaml:"owner_patronymic"`
+	// rules of the car fields by their json paths, the regexes above are used if the rule doesnt contain regex
+	Rules map[string]FieldRule `yaml:"rules"`
+	// codes of the military plates, their text is the same as the text of the motorcycle plates
+	PlateMilitaryCodes []string `yaml:"plate_military_codes"`
+}
+
+// FieldRule describes checks of the field, zero values mean no check.
+// Lengths and enum are checked for the strings, min and max for the numbers
+type FieldRule struct {
+	Required  bool     `yaml:"required" json:"required"`
+	Regex     string   `yaml:"regex" json:"regex"`
+	MinLength int      `yaml:"min_length" json:"min_length"`
+	MaxLength int      `yaml:"max_length" json:"max_length"`
+	Enum      []string `yaml:"enum" json:"enum"`
+	Min       *int     `yaml:"min" json:"min"`
+	Max       *int     `yaml:"max" json:"max"`
+	// built-in check of the russian registration plate, only for the register number
+	Plate bool `yaml:"plate" json:"plate"`
+}
+
+// FieldRegex returns regex of the field from the rules or from the old regex settings
+func (v *ValidatorConfig) FieldRegex(field string) string {
+	if rule, ok := v.Rules[field]; ok && rule.Regex != "" {
+		return rule.Regex
+	}
+	switch field {
+	case "regNum":
+		return v.RegisterNumberRegex
+	case "mark":
+		return v.MarkRegex
+	case "model":
+		return v.ModelRegex
+	case "owner.name":
+		return v.OwnerNameRegex
+	case "owner.surname":
+		return v.OwnerSurnameRegex
+	case "owner.patronymic":
+		return v.OwnerPatronymicRegex
+	}
+	return ""
 }
 
-func (v *ValidatorConfig) checkRegex() error {
-	b, _ := json.Marshal(v)
-	var m map[string]interface{}
-	_ = json.Unmarshal(b, &m)
-	for i, v := range m {
-		_, err := regexp.Compile(v.(string))
-		if err != nil {
-			return fmt.Errorf("incorrect %s", i)
+func (v *ValidatorConfig) checkRules() error {
+	for field, rule := range v.Rules {
+		if !isValidatedField(field) {
+			return fmt.Errorf("unknown validated field %s", field)
+		}
+		if rule.Plate && field != "regNum" {
+			return fmt.Errorf("plate rule is used for %s, it can be used only for regNum", field)
+		}
+	}
+	for _, code := range v.PlateMilitaryCodes {
+		if len(code) != 2 || code[0] < '0' || code[0] > '9' || code[1] < '0' || code[1] > '9' {
+			return fmt.Errorf("incorrect military plate code %s", code)
+		}
+	}
+	for _, field := range ValidatedCarFields {
+		if _, err := regexp.Compile(v.FieldRegex(field)); err != nil {
+			return fmt.Errorf("incorrect regex of %s", field)
+		}
+		rule := v.Rules[field]
+		if rule.MinLength < 0 || rule.MaxLength < 0 || (rule.MaxLength != 0 && rule.MinLength > rule.MaxLength) {
+			return fmt.Errorf("incorrect length limits of %s", field)
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return fmt.Errorf("incorrect range of %s", field)
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func isValidatedField(field string) bool {
+	for _, name := range ValidatedCarFields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
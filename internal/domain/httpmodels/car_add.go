@@ -3,3 +3,12 @@ package httpmodels
 type CarAddRequest struct {
 	RegisterNumbers []string `json:"regNums"`
 }
+
+// CarAddResponse contains ids of the added cars and of the existing cars which were skipped,
+// register numbers of the cars with the vin of the other car and of the repeated cars arent added
+type CarAddResponse struct {
+	Inserted     []int    `json:"inserted"`
+	Skipped      []int    `json:"skipped"`
+	VinConflicts []string `json:"vinConflicts"`
+	Duplicates   []string `json:"duplicates"`
+}
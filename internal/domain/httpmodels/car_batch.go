@@ -0,0 +1,26 @@
+package httpmodels
+
+import "encoding/json"
+
+// filter has the same fields and value format as the query filter of getting cars,
+// for example {"mark": ["Lada"], "year": ["gt:2000"]}
+
+type CarBatchEditRequest struct {
+	Ids        []int               `json:"ids"`
+	Filter     map[string][]string `json:"filter"`
+	CarNewData json.RawMessage     `json:"carNewData"`
+}
+
+type CarBatchDeleteRequest struct {
+	Ids          []int               `json:"ids"`
+	Filter       map[string][]string `json:"filter"`
+	ConfirmToken string              `json:"confirmToken"`
+}
+
+// CarBatchResponse contains ids of the changed cars or of the cars which will be changed in dry run
+type CarBatchResponse struct {
+	DryRun       bool   `json:"dryRun"`
+	Matched      int    `json:"matched"`
+	Ids          []int  `json:"ids"`
+	ConfirmToken string `json:"confirmToken,omitempty"`
+}
@@ -1,7 +0,0 @@
-package httpmodels
-
-import "github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
-
-type CarEditRequest struct {
-	CarNewData models.CarForPatch `json:"carNewData"`
-}
\ No newline at end of file
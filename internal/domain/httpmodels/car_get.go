@@ -1,11 +1,31 @@
 package httpmodels
 
-import "github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+import (
+	"encoding/json"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
 
 type CarGetOneResponse struct {
 	Car models.Car `json:"car"`
 }
 
+// CarGetAllResponse contains facets of the filtered cars if they are requested
 type CarGetAllResponse struct {
-	Cars []models.Car `json:"cars"`
+	Cars   []models.Car                   `json:"cars"`
+	Facets map[string][]models.FacetValue `json:"facets,omitempty"`
+}
+
+// CarGetAllSparseResponse contains only the fields selected by the client
+type CarGetAllSparseResponse struct {
+	Cars   []map[string]json.RawMessage   `json:"cars"`
+	Facets map[string][]models.FacetValue `json:"facets,omitempty"`
+}
+
+type CarStatsResponse struct {
+	Stats models.CarStats `json:"stats"`
+}
+
+type CarSuggestResponse struct {
+	Suggestions []models.Suggestion `json:"suggestions"`
 }
\ No newline at end of file
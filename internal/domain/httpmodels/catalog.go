@@ -0,0 +1,25 @@
+package httpmodels
+
+import "github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+
+// CatalogEntryRequest is the new mark or model, aliases are other spellings of the name
+type CatalogEntryRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+type CatalogGetResponse struct {
+	Marks []models.CatalogMark `json:"marks"`
+}
+
+type CatalogMarkResponse struct {
+	Mark models.CatalogMark `json:"mark"`
+}
+
+type CatalogModelResponse struct {
+	Model models.CatalogModel `json:"model"`
+}
+
+type CatalogReportResponse struct {
+	Unmatched []models.UnmatchedValue `json:"unmatched"`
+}
@@ -1,22 +1,56 @@
 package models
 
+import "time"
+
 // we will store full name in record of car, of course better to create
 // individual table for owners and store there only id of owner object
 
+// sources of the car record
+const (
+	SourceExternalApi = "external_api"
+	SourceManual      = "manual"
+	SourceImport      = "import"
+	SourceResync      = "resync"
+)
+
 type Car struct {
-	Id             int    `json:"carId"`
-	RegisterNumber string `json:"regNum"`
-	Mark           string `json:"mark"`
-	Model          string `json:"model"`
-	Year           uint16 `json:"year"`
-	Owner          Owner  `json:"owner"`
+	Id             int        `json:"carId"`
+	RegisterNumber string     `json:"regNum"`
+	Mark           string     `json:"mark"`
+	Model          string     `json:"model"`
+	Year           uint16     `json:"year"`
+	Owner          *Owner     `json:"owner,omitempty"`
+	// parsed register number, nil if it isnt russian registration plate
+	Plate          *Plate     `json:"plate,omitempty"`
+	// vehicle identification number, it is kept when the plate changes
+	Vin            *string    `json:"vin"`
+	// decoded vin, nil if the vin is missing
+	VinInfo        *VinInfo   `json:"vinInfo,omitempty"`
+	Source         string     `json:"source"`
+	FetchedAt      *time.Time `json:"fetchedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	UpdatedBy      *string    `json:"updatedBy"`
 }
 
+// CarForPatch contains only the changed fields of the car, nil fields are kept as is
 type CarForPatch struct {
-	Id             *string        `json:"carId"`
 	RegisterNumber *string        `json:"regNum"`
 	Mark           *string        `json:"mark"`
 	Model          *string        `json:"model"`
 	Year           *uint16        `json:"year"`
 	Owner          *OwnerForPatch `json:"owner"`
+	Vin            *string        `json:"vin"`
+	// vin is set to null
+	ClearVin bool `json:"-"`
+	// provenance data and parsed register number, filled by the service and not by the client
+	Source    string  `json:"-"`
+	UpdatedBy *string `json:"-"`
+	Plate     *Plate  `json:"-"`
+}
+
+// IsEmpty returns true if the patch doesnt change any field
+func (c CarForPatch) IsEmpty() bool {
+	return c.RegisterNumber == nil && c.Mark == nil && c.Model == nil && c.Year == nil &&
+		c.Vin == nil && !c.ClearVin && (c.Owner == nil || c.Owner.IsEmpty())
 }
\ No newline at end of file
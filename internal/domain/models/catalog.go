@@ -0,0 +1,40 @@
+package models
+
+// CatalogMark is the canonical mark of the cars, aliases are other spellings of the mark
+// which are replaced by its name
+type CatalogMark struct {
+	Id      int            `json:"markId"`
+	Name    string         `json:"name"`
+	Aliases []string       `json:"aliases"`
+	Models  []CatalogModel `json:"models"`
+}
+
+// CatalogModel is the canonical model of the mark
+type CatalogModel struct {
+	Id      int      `json:"modelId"`
+	MarkId  int      `json:"markId"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// MarkModelCount is the number of the cars with the mark and model
+type MarkModelCount struct {
+	Mark  string
+	Model string
+	Count int
+}
+
+// fields of the unmatched values
+const (
+	UnmatchedMark  = "mark"
+	UnmatchedModel = "model"
+)
+
+// UnmatchedValue is the mark or model which doesnt match any catalog entry,
+// mark of the unmatched model is set if it is known
+type UnmatchedValue struct {
+	Field string `json:"field"`
+	Mark  string `json:"mark,omitempty"`
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
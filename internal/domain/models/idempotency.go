@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the saved response of the request with Idempotency-Key header,
+// zero status means that the first request is still processed
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
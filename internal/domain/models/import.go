@@ -0,0 +1,34 @@
+package models
+
+// ImportRow is the parsed and validated row of the imported file
+type ImportRow struct {
+	// line of the row in the file, starting from 1
+	Line   int
+	Car    Car
+	Errors []FieldError
+}
+
+// ImportRowError describes the row which wasnt imported
+type ImportRowError struct {
+	Line           int          `json:"line"`
+	RegisterNumber string       `json:"regNum,omitempty"`
+	Errors         []FieldError `json:"errors"`
+}
+
+// ImportReport is the result of the cars import, saved cars are the inserted and updated ones
+type ImportReport struct {
+	DryRun     bool             `json:"dryRun"`
+	OnConflict ConflictPolicy   `json:"onConflict"`
+	Total      int              `json:"total"`
+	Valid      int              `json:"valid"`
+	Invalid    int              `json:"invalid"`
+	Saved      int              `json:"saved"`
+	Inserted   int              `json:"inserted"`
+	Updated    int              `json:"updated"`
+	Skipped    int              `json:"skipped"`
+	Errors     []ImportRowError `json:"errors"`
+	// line of the first row which isnt saved when the import is stopped by the error, rows before it are processed
+	StoppedAtLine int `json:"stoppedAtLine,omitempty"`
+	// marks and models of the valid rows which dont match the catalog, they are saved as is
+	Unmatched []UnmatchedValue `json:"unmatched"`
+}
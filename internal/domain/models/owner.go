@@ -10,4 +10,10 @@ type OwnerForPatch struct {
 	Name       *string `json:"name"`
 	Surname    *string `json:"surname"`
 	Patronymic *string `json:"patronymic"`
+	// patronymic is set to null, it is used because nil Patronymic means no change
+	ClearPatronymic bool `json:"-"`
+}
+
+func (o OwnerForPatch) IsEmpty() bool {
+	return o.Name == nil && o.Surname == nil && o.Patronymic == nil && !o.ClearPatronymic
 }
\ No newline at end of file
@@ -3,4 +3,12 @@ package models
 type PaginationOption struct {
 	Limit  int
 	Offset int
-}
\ No newline at end of file
+	Sort   []SortField
+	// json names of the selected car fields, empty means all fields
+	Fields []string
+}
+
+type SortField struct {
+	Name string
+	Desc bool
+}
@@ -0,0 +1,41 @@
+package models
+
+import "strings"
+
+// types of the russian registration plates
+const (
+	PlateTypePrivate    = "private"
+	PlateTypeTaxi       = "taxi"
+	PlateTypeTrailer    = "trailer"
+	PlateTypeMotorcycle = "motorcycle"
+	PlateTypeDiplomatic = "diplomatic"
+	PlateTypeMilitary   = "military"
+)
+
+// Plate is the parsed register number. Letters of the series are cyrillic, number of the diplomatic
+// plates contains the code of the mission and the number separated by dash
+type Plate struct {
+	Type   string `json:"type"`
+	Series string `json:"series"`
+	Number string `json:"number"`
+	Region string `json:"region"`
+}
+
+// Text returns the plate without spaces, the same plate typed in the other case or with
+// the latin letters has the same text, so it is used as the register number
+func (p Plate) Text() string {
+	switch p.Type {
+	case PlateTypePrivate:
+		series := []rune(p.Series)
+		if len(series) == 0 {
+			break
+		}
+		return string(series[:1]) + p.Number + string(series[1:]) + p.Region
+	case PlateTypeMotorcycle, PlateTypeMilitary:
+		return p.Number + p.Series + p.Region
+	case PlateTypeDiplomatic:
+		mission, number, _ := strings.Cut(p.Number, "-")
+		return mission + p.Series + number + p.Region
+	}
+	return p.Series + p.Number + p.Region
+}
@@ -0,0 +1,32 @@
+package models
+
+// ConflictPolicy describes what to do with the saved car if the car with its register number already exists
+type ConflictPolicy string
+
+const (
+	// existing car is kept as is
+	ConflictSkip ConflictPolicy = "skip"
+	// fields of the existing car are replaced by the new ones
+	ConflictUpdate ConflictPolicy = "update"
+	// nothing is saved if any car already exists
+	ConflictFail ConflictPolicy = "fail"
+)
+
+func (cp ConflictPolicy) Valid() bool {
+	switch cp {
+	case ConflictSkip, ConflictUpdate, ConflictFail:
+		return true
+	}
+	return false
+}
+
+// SaveResult contains ids of the saved cars separated by the way they were saved
+type SaveResult struct {
+	Inserted []int `json:"inserted"`
+	Updated  []int `json:"updated"`
+	Skipped  []int `json:"skipped"`
+	// positions of the cars in the saved list which arent saved, because their vin is used by other car
+	VinConflicts []int `json:"vinConflicts"`
+	// positions of the cars in the saved list which arent saved, because the list already contains their register number
+	Duplicates []int `json:"duplicates"`
+}
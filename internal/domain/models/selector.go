@@ -0,0 +1,7 @@
+package models
+
+// CarSelector selects cars of the batch operation by ids or by filter, ids take precedence
+type CarSelector struct {
+	Ids    []int
+	Filter Filter
+}
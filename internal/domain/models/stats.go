@@ -0,0 +1,48 @@
+package models
+
+// dimensions of the cars facets
+const (
+	FacetMark        = "mark"
+	FacetModel       = "model"
+	FacetYear        = "year"
+	FacetPlateRegion = "plate_region"
+)
+
+// aggregates of the cars stats
+const (
+	AggregateAvgAge  = "avg_age"
+	AggregateMinYear = "min_year"
+	AggregateMaxYear = "max_year"
+)
+
+// StatsOption contains requested facets and aggregates. Years are grouped by YearBucket years,
+// every facet except the year contains at most Limit most used values, zero limit means all values
+type StatsOption struct {
+	Facets     []string
+	Aggregates []string
+	YearBucket int
+	Limit      int
+}
+
+// Aggregates contains requested aggregates of the cars, not requested ones are nil
+type Aggregates struct {
+	AvgAge  *float64 `json:"avgAge,omitempty"`
+	MinYear *int     `json:"minYear,omitempty"`
+	MaxYear *int     `json:"maxYear,omitempty"`
+}
+
+// FacetValue is the number of the cars with the value of the dimension, the model also contains its mark.
+// Value of the year bucket is the range like 2000-2009
+type FacetValue struct {
+	Value string `json:"value"`
+	Mark  string `json:"mark,omitempty"`
+	Count int    `json:"count"`
+	Aggregates
+}
+
+// CarStats contains the number and aggregates of the filtered cars and their facets by the dimension names
+type CarStats struct {
+	Total int `json:"total"`
+	Aggregates
+	Facets map[string][]FacetValue `json:"facets"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// fields which values can be suggested, names are the same as in the filter
+const (
+	SuggestMark         = "mark"
+	SuggestModel        = "model"
+	SuggestOwnerName    = "owner_name"
+	SuggestOwnerSurname = "owner_surname"
+)
+
+// SuggestQuery selects the values of the field which start with the prefix, the case is ignored.
+// Mark limits the suggested models by the models of the mark
+type SuggestQuery struct {
+	Field  string
+	Prefix string
+	Mark   string
+	Limit  int
+}
+
+// Suggestion is the value of the field and the number of the cars with it
+type Suggestion struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// rules of the field errors
+const (
+	RuleRequired  = "required"
+	RuleRegex     = "regex"
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleEnum      = "enum"
+	RuleMin       = "min"
+	RuleMax       = "max"
+	RulePlate     = "plate"
+	RuleVin       = "vin"
+	// errors of the request format
+	RuleType     = "type"
+	RuleNull     = "null"
+	RuleUnknown  = "unknown"
+	RuleReadOnly = "read_only"
+	RuleEmpty    = "empty"
+	RuleFormat   = "format"
+	RuleUnique   = "unique"
+)
+
+// FieldError describes the field which didnt pass validation, field is the json path like owner.name
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
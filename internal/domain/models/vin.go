@@ -0,0 +1,20 @@
+package models
+
+// regions of the manufacturers by the first symbol of the vin
+const (
+	VinRegionAfrica       = "africa"
+	VinRegionAsia         = "asia"
+	VinRegionEurope       = "europe"
+	VinRegionNorthAmerica = "north_america"
+	VinRegionOceania      = "oceania"
+	VinRegionSouthAmerica = "south_america"
+)
+
+// VinInfo is decoded from the vin and cant be changed by the client.
+// Manufacturer is empty if the WMI is unknown, model year is nil if the 10th symbol doesnt encode it
+type VinInfo struct {
+	Wmi          string `json:"wmi"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Region       string `json:"region"`
+	ModelYear    *int   `json:"modelYear,omitempty"`
+}
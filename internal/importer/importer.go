@@ -0,0 +1,279 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+// formats of the imported files
+const (
+	FormatCsv    = "csv"
+	FormatNdjson = "ndjson"
+)
+
+// max length of the ndjson line
+const maxLineSize = 1024 * 1024
+
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// csv columns, the same names are used by the export
+const (
+	columnRegNum          = "regNum"
+	columnMark            = "mark"
+	columnModel           = "model"
+	columnYear            = "year"
+	columnOwnerName       = "ownerName"
+	columnOwnerSurname    = "ownerSurname"
+	columnOwnerPatronymic = "ownerPatronymic"
+	columnVin             = "vin"
+)
+
+var requiredColumns = []string{
+	columnRegNum,
+	columnMark,
+	columnModel,
+	columnYear,
+	columnOwnerName,
+	columnOwnerSurname,
+}
+
+// RowReader returns rows of the file one by one, io.EOF means the end of the file.
+// Invalid rows are returned with errors, other errors mean that the file cant be read further
+type RowReader interface {
+	Next() (models.ImportRow, error)
+}
+
+// CarValidator checks the car of the row
+type CarValidator interface {
+	ValidateCar(models.Car) []models.FieldError
+}
+
+// NewRowReader creates reader of the file in the format which validates every row
+func NewRowReader(format string, r io.Reader, validator CarValidator) (RowReader, error) {
+	switch format {
+	case FormatCsv:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		return &csvReader{reader: reader, validator: validator}, nil
+	case FormatNdjson:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return &ndjsonReader{scanner: scanner, validator: validator}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+type csvReader struct {
+	reader    *csv.Reader
+	validator CarValidator
+	// positions of the columns, read from the header
+	columns map[string]int
+}
+
+func (cr *csvReader) Next() (models.ImportRow, error) {
+	if cr.columns == nil {
+		if err := cr.readHeader(); err != nil {
+			return models.ImportRow{}, err
+		}
+	}
+	record, err := cr.reader.Read()
+	if err != nil {
+		// reader continues from the next line after the broken one
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return models.ImportRow{Line: parseErr.StartLine, Errors: []models.FieldError{{
+				Rule:    models.RuleFormat,
+				Message: parseErr.Err.Error(),
+			}}}, nil
+		}
+		return models.ImportRow{}, err
+	}
+	line, _ := cr.reader.FieldPos(0)
+	row := models.ImportRow{Line: line}
+	value := func(column string) string {
+		pos, ok := cr.columns[column]
+		if !ok || pos >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[pos])
+	}
+	row.Car = models.Car{
+		RegisterNumber: value(columnRegNum),
+		Mark:           value(columnMark),
+		Model:          value(columnModel),
+		Owner: &models.Owner{
+			Name:    value(columnOwnerName),
+			Surname: value(columnOwnerSurname),
+		},
+	}
+	if patronymic := value(columnOwnerPatronymic); patronymic != "" {
+		row.Car.Owner.Patronymic = &patronymic
+	}
+	if vin := value(columnVin); vin != "" {
+		row.Car.Vin = &vin
+	}
+	validYear := true
+	if year := value(columnYear); year != "" {
+		parsed, err := strconv.ParseUint(year, 10, 16)
+		if err != nil {
+			validYear = false
+			row.Errors = append(row.Errors, models.FieldError{
+				Field:   "year",
+				Rule:    models.RuleType,
+				Message: "not valid type of year",
+			})
+		}
+		row.Car.Year = uint16(parsed)
+	}
+	for _, fieldErr := range cr.validator.ValidateCar(row.Car) {
+		// not parsed year is already reported
+		if validYear || fieldErr.Field != "year" {
+			row.Errors = append(row.Errors, fieldErr)
+		}
+	}
+	return row, nil
+}
+
+func (cr *csvReader) readHeader() error {
+	header, err := cr.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("missing csv header")
+		}
+		return err
+	}
+	cr.columns = make(map[string]int, len(header))
+	for i, column := range header {
+		// unknown columns like carId of the exported file are skipped
+		cr.columns[strings.TrimSpace(column)] = i
+	}
+	for _, column := range requiredColumns {
+		if _, ok := cr.columns[column]; !ok {
+			return fmt.Errorf("missing csv column %s", column)
+		}
+	}
+	return nil
+}
+
+// fields of the exported cars which are filled by the service and cant be imported
+var readOnlyFields = map[string]struct{}{
+	"carId":     {},
+	"plate":     {},
+	"vinInfo":   {},
+	"source":    {},
+	"fetchedAt": {},
+	"createdAt": {},
+	"updatedAt": {},
+	"updatedBy": {},
+}
+
+// ndjsonCar is the imported car, it contains only the fields set by the client
+type ndjsonCar struct {
+	RegisterNumber string        `json:"regNum"`
+	Mark           string        `json:"mark"`
+	Model          string        `json:"model"`
+	Year           uint16        `json:"year"`
+	Owner          *models.Owner `json:"owner"`
+	Vin            *string       `json:"vin"`
+}
+
+// ndjsonFields and ndjsonOwnerFields are the keys of ndjsonCar
+var (
+	ndjsonFields      = map[string]struct{}{"regNum": {}, "mark": {}, "model": {}, "year": {}, "owner": {}, "vin": {}}
+	ndjsonOwnerFields = map[string]struct{}{"name": {}, "surname": {}, "patronymic": {}}
+)
+
+type ndjsonReader struct {
+	scanner   *bufio.Scanner
+	validator CarValidator
+	line      int
+}
+
+func (nr *ndjsonReader) Next() (models.ImportRow, error) {
+	for nr.scanner.Scan() {
+		nr.line++
+		data := strings.TrimSpace(nr.scanner.Text())
+		if data == "" {
+			continue
+		}
+		row := models.ImportRow{Line: nr.line}
+		row.Car, row.Errors = decodeNdjsonCar([]byte(data))
+		if len(row.Errors) == 0 {
+			row.Errors = nr.validator.ValidateCar(row.Car)
+		}
+		return row, nil
+	}
+	if err := nr.scanner.Err(); err != nil {
+		return models.ImportRow{}, err
+	}
+	return models.ImportRow{}, io.EOF
+}
+
+// decodeNdjsonCar decodes the line into the car, all unknown and read only keys are returned as errors
+func decodeNdjsonCar(data []byte) (models.Car, []models.FieldError) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return models.Car{}, []models.FieldError{{Rule: models.RuleFormat, Message: "not valid json"}}
+	}
+	errs := unknownKeys("", fields, ndjsonFields)
+	var owner map[string]json.RawMessage
+	// not valid owner is reported by the decoding
+	if json.Unmarshal(fields["owner"], &owner) == nil {
+		errs = append(errs, unknownKeys("owner.", owner, ndjsonOwnerFields)...)
+	}
+	if len(errs) != 0 {
+		return models.Car{}, errs
+	}
+	var car ndjsonCar
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&car); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return models.Car{}, []models.FieldError{{
+				Field:   typeErr.Field,
+				Rule:    models.RuleType,
+				Message: "not valid type of " + typeErr.Field,
+			}}
+		}
+		return models.Car{}, []models.FieldError{{Rule: models.RuleFormat, Message: err.Error()}}
+	}
+	return models.Car{
+		RegisterNumber: car.RegisterNumber,
+		Mark:           car.Mark,
+		Model:          car.Model,
+		Year:           car.Year,
+		Owner:          car.Owner,
+		Vin:            car.Vin,
+	}, nil
+}
+
+// unknownKeys returns errors of the keys which arent known, the read only fields of the car are reported separately
+func unknownKeys(prefix string, fields map[string]json.RawMessage, known map[string]struct{}) []models.FieldError {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if _, ok := known[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var errs []models.FieldError
+	for _, name := range names {
+		if _, ok := readOnlyFields[name]; ok && prefix == "" {
+			errs = append(errs, models.FieldError{Field: name, Rule: models.RuleReadOnly, Message: fmt.Sprintf("%s cant be imported", name)})
+			continue
+		}
+		errs = append(errs, models.FieldError{Field: prefix + name, Rule: models.RuleUnknown, Message: fmt.Sprintf("unknown field %s%s", prefix, name)})
+	}
+	return errs
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/validator"
+)
+
+var testValidCfg = config.ValidatorConfig{
+	RegisterNumberRegex:  "^[A-Z][0-9]{3}[A-Z]{2}[0-9]{2,3}$",
+	MarkRegex:            "^.+$",
+	ModelRegex:           "^.+$",
+	OwnerNameRegex:       "^[A-Za-z]+$",
+	OwnerSurnameRegex:    "^[A-Za-z]+$",
+	OwnerPatronymicRegex: "^[A-Za-z]+$",
+}
+
+func newTestValidator(t *testing.T) CarValidator {
+	t.Helper()
+	carValidator, err := validator.New(testValidCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return carValidator
+}
+
+// readAll returns rules of the row errors by the lines
+func readAll(t *testing.T, rows RowReader) map[int][]string {
+	t.Helper()
+	errs := make(map[int][]string)
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			return errs
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		var rules []string
+		for _, fieldErr := range row.Errors {
+			rules = append(rules, fieldErr.Field+":"+fieldErr.Rule)
+		}
+		errs[row.Line] = rules
+	}
+}
+
+func TestCsvReader(t *testing.T) {
+	data := "carId,regNum,mark,model,year,ownerName,ownerSurname,ownerPatronymic\n" +
+		"1,X123XX150,Lada,Vesta,2002,Ivan,Ivanov,\n" +
+		"2,bad,Lada,Vesta,1800,Ivan,Ivanov,Ivanovich\n" +
+		"3,Y123YY150,Lada,Vesta,year,Ivan,Ivanov,\n"
+	rows, err := NewRowReader(FormatCsv, strings.NewReader(data), newTestValidator(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int][]string{
+		2: nil,
+		3: {"regNum:regex", "year:min"},
+		4: {"year:type"},
+	}
+	if got := readAll(t, rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestCsvReaderMissingColumn(t *testing.T) {
+	rows, _ := NewRowReader(FormatCsv, strings.NewReader("regNum,mark\nX123XX150,Lada\n"), newTestValidator(t))
+	if _, err := rows.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected error of the missing column, got %v", err)
+	}
+}
+
+func TestNdjsonReader(t *testing.T) {
+	data := `{"regNum":"X123XX150","mark":"Lada","model":"Vesta","year":2002,"owner":{"name":"Ivan","surname":"Ivanov"}}` + "\n" +
+		"\n" +
+		`{"regNum":"X123XX150","mark":"Lada"` + "\n" +
+		`{"regNum":"X123XX150","mark":"Lada","model":"Vesta","year":2002}` + "\n" +
+		`{"carId":4,"regNum":"X123XX150","mark":"Lada","model":"Vesta","year":2002,"owner":{"name":"Ivan","surname":"Ivanov"},"source":"manual","createdAt":"2024-01-01T10:00:00Z","vinInfo":null,"plate":null}` + "\n" +
+		`{"regNum":"X123XX150","mark":"Lada","model":"Vesta","year":2002,"owner":{"name":"Ivan","surname":"Ivanov","age":30},"color":"red"}` + "\n" +
+		`{"regNum":"X123XX150","mark":"Lada","model":"Vesta","year":"2002","owner":{"name":"Ivan","surname":"Ivanov"}}` + "\n" +
+		`{"regNum":"X123XX150","mark":"Lada","model":"Vesta","year":2002,"owner":{"name":1,"surname":"Ivanov"}}` + "\n" +
+		`[]` + "\n"
+	rows, err := NewRowReader(FormatNdjson, strings.NewReader(data), newTestValidator(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int][]string{
+		1: nil,
+		3: {":format"},
+		4: {"owner.name:required", "owner.surname:required"},
+		5: {"carId:read_only", "createdAt:read_only", "plate:read_only", "source:read_only", "vinInfo:read_only"},
+		6: {"color:unknown", "owner.age:unknown"},
+		7: {"year:type"},
+		8: {"owner.name:type"},
+		9: {":format"},
+	}
+	if got := readAll(t, rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
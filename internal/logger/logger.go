@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"log/slog"
 	"os"
 )
@@ -22,4 +23,24 @@ var log *slog.Logger
 		)
 	}
 	return log
-}
\ No newline at end of file
+}
+
+type ctxKey struct{}
+
+// ContextWithLogger returns context which contains request scoped logger
+func ContextWithLogger(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns request scoped logger with additional attributes,
+// if context doesnt contain logger then fallback is returned as is
+func FromContext(ctx context.Context, fallback *slog.Logger, args ...any) *slog.Logger {
+	log, ok := ctx.Value(ctxKey{}).(*slog.Logger)
+	if !ok {
+		return fallback
+	}
+	if len(args) == 0 {
+		return log
+	}
+	return log.With(args...)
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cars"
+
+var registry = prometheus.NewRegistry()
+
+// http server metrics
+var (
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of the handled http requests.",
+	}, []string{"method", "route", "status"})
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the http requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	RateLimitRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "ratelimit_rejected_total",
+		Help:      "Number of the requests rejected by the rate limiter.",
+	}, []string{"route"})
+)
+
+// database metrics
+var (
+	DbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of the storage methods.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	DbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Number of the failed storage methods.",
+	}, []string{"method"})
+)
+
+// upstream metrics
+var (
+	UpstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the upstream requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"upstream"})
+	UpstreamResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "responses_total",
+		Help:      "Number of the upstream responses by status, transport errors have error status.",
+	}, []string{"upstream", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequests,
+		HttpRequestDuration,
+		RateLimitRejected,
+		DbQueryDuration,
+		DbQueryErrors,
+		UpstreamRequestDuration,
+		UpstreamResponses,
+	)
+}
+
+// Handler returns http handler which exposes metrics in prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterPoolStats registers metrics of the database connection pool
+func RegisterPoolStats(stat func() *pgxpool.Stat) {
+	registry.MustRegister(&poolCollector{stat: stat})
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolTotalConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "total_connections"),
+		"Number of the connections in the pool.", nil, nil)
+	poolAcquiredConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquired_connections"),
+		"Number of the connections which are used now.", nil, nil)
+	poolIdleConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "idle_connections"),
+		"Number of the idle connections.", nil, nil)
+	poolMaxConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "max_connections"),
+		"Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquires_total"),
+		"Number of the successful acquires from the pool.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "empty_acquires_total"),
+		"Number of the acquires which waited for the connection.", nil, nil)
+	poolCanceledAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "canceled_acquires_total"),
+		"Number of the acquires canceled by the context.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquire_duration_seconds_total"),
+		"Total time of the acquires.", nil, nil)
+)
+
+// poolCollector reads stats of the pool on every scrape
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+}
+
+func (pc *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolTotalConns
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolMaxConns
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolCanceledAcquires
+	ch <- poolAcquireDuration
+}
+
+func (pc *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := pc.stat()
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+var (
+	errInvalidApiKey = errors.New("invalid api key")
+	errInvalidToken  = errors.New("invalid bearer token")
+)
+
+type apiKey struct {
+	hash      [sha256.Size]byte
+	principal auth.Principal
+}
+
+// jwtClaims are registered claims with the space separated scopes
+type jwtClaims struct {
+	Scope string `json:"scope"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type authenticator struct {
+	enabled bool
+	apiKeys []apiKey
+	secret  []byte
+	parser  *jwt.Parser
+}
+
+func NewAuthenticator(cfg config.AuthConfig) *authenticator {
+	a := &authenticator{
+		enabled: cfg.Enabled,
+	}
+	for _, key := range cfg.ApiKeys {
+		a.apiKeys = append(a.apiKeys, apiKey{
+			hash: sha256.Sum256([]byte(key.Key)),
+			principal: auth.Principal{
+				Name:   key.Name,
+				Role:   key.Role,
+				Scopes: key.Scopes,
+			},
+		})
+	}
+	if cfg.JwtSecret != "" {
+		a.secret = []byte(cfg.JwtSecret)
+		options := []jwt.ParserOption{
+			jwt.WithValidMethods([]string{
+				jwt.SigningMethodHS256.Alg(),
+				jwt.SigningMethodHS384.Alg(),
+				jwt.SigningMethodHS512.Alg(),
+			}),
+			jwt.WithExpirationRequired(),
+		}
+		if cfg.JwtIssuer != "" {
+			options = append(options, jwt.WithIssuer(cfg.JwtIssuer))
+		}
+		if cfg.JwtAudience != "" {
+			options = append(options, jwt.WithAudience(cfg.JwtAudience))
+		}
+		a.parser = jwt.NewParser(options...)
+	}
+	return a
+}
+
+// authenticate returns principal of the request,
+// ok is false if request doesnt contain credentials
+func (a *authenticator) authenticate(r *http.Request) (auth.Principal, bool, error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		principal, err := a.checkApiKey(key)
+		return principal, true, err
+	}
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return auth.Principal{}, false, nil
+	}
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return auth.Principal{}, true, errInvalidToken
+	}
+	principal, err := a.checkToken(strings.TrimPrefix(header, bearerPrefix))
+	return principal, true, err
+}
+
+func (a *authenticator) checkApiKey(key string) (auth.Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	for _, apiKey := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], apiKey.hash[:]) == 1 {
+			return apiKey.principal, nil
+		}
+	}
+	return auth.Principal{}, errInvalidApiKey
+}
+
+func (a *authenticator) checkToken(token string) (auth.Principal, error) {
+	if a.parser == nil {
+		return auth.Principal{}, errInvalidToken
+	}
+	claims := &jwtClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	})
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return auth.Principal{}, fmt.Errorf("%w: empty subject", errInvalidToken)
+	}
+	return auth.Principal{
+		Name:   claims.Subject,
+		Role:   claims.Role,
+		Scopes: strings.Fields(claims.Scope),
+	}, nil
+}
+
+// Middleware puts principal of the request to the context, requests with invalid credentials are rejected.
+// Requests without credentials are passed as anonymous only to the public paths, so the route without Require
+// is still closed. Public path which ends with slash matches all paths with this prefix
+func (a *authenticator) Middleware(log *slog.Logger, publicPaths ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !a.enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+			principal, ok, err := a.authenticate(r)
+			if err != nil {
+				l.FromContext(r.Context(), log).Info("authentication failed", slog.String("error", err.Error()))
+				unauthorized(w, r, err.Error())
+				return
+			}
+			if !ok {
+				if !isPublicPath(r.URL.Path, publicPaths) {
+					unauthorized(w, r, "authentication required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := auth.ContextWithPrincipal(r.Context(), principal)
+			reqLog := l.FromContext(ctx, log, slog.String("principal", principal.Name))
+			ctx = l.ContextWithLogger(ctx, reqLog)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func isPublicPath(path string, publicPaths []string) bool {
+	for _, public := range publicPaths {
+		if path == public || (strings.HasSuffix(public, "/") && strings.HasPrefix(path, public)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Require rejects requests of the principals which dont have the scope
+func (a *authenticator) Require(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	if !a.enabled {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			unauthorized(w, r, "authentication required")
+			return
+		}
+		if !principal.HasScope(scope) {
+			problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("scope %s required", scope))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="cars"`)
+	problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, detail)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	testSecret     = "test-secret"
+	testAuthConfig = config.AuthConfig{
+		Enabled: true,
+		ApiKeys: []config.ApiKeyConfig{
+			{
+				Name:   "reader",
+				Key:    "reader-key",
+				Scopes: []string{"cars:read"},
+			},
+		},
+		JwtSecret: testSecret,
+		JwtIssuer: "test-issuer",
+	}
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, secret string, claims jwtClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("cant sign token: %s", err.Error())
+	}
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := NewAuthenticator(testAuthConfig)
+	validClaims := jwtClaims{
+		Scope: "cars:read cars:write",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user",
+			Issuer:    "test-issuer",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	expiredClaims := validClaims
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongIssuerClaims := validClaims
+	wrongIssuerClaims.Issuer = "other"
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		wantOk    bool
+		wantErr   bool
+		wantName  string
+		wantScope string
+	}{
+		{
+			name:   "no credentials",
+			wantOk: false,
+		},
+		{
+			name:      "valid api key",
+			headers:   map[string]string{apiKeyHeader: "reader-key"},
+			wantOk:    true,
+			wantName:  "reader",
+			wantScope: "cars:read",
+		},
+		{
+			name:    "invalid api key",
+			headers: map[string]string{apiKeyHeader: "other-key"},
+			wantOk:  true,
+			wantErr: true,
+		},
+		{
+			name:      "valid token",
+			headers:   map[string]string{"Authorization": bearerPrefix + signToken(t, jwt.SigningMethodHS256, testSecret, validClaims)},
+			wantOk:    true,
+			wantName:  "user",
+			wantScope: "cars:write",
+		},
+		{
+			name:    "token with wrong secret",
+			headers: map[string]string{"Authorization": bearerPrefix + signToken(t, jwt.SigningMethodHS256, "other", validClaims)},
+			wantOk:  true,
+			wantErr: true,
+		},
+		{
+			name:    "expired token",
+			headers: map[string]string{"Authorization": bearerPrefix + signToken(t, jwt.SigningMethodHS256, testSecret, expiredClaims)},
+			wantOk:  true,
+			wantErr: true,
+		},
+		{
+			name:    "token with wrong issuer",
+			headers: map[string]string{"Authorization": bearerPrefix + signToken(t, jwt.SigningMethodHS256, testSecret, wrongIssuerClaims)},
+			wantOk:  true,
+			wantErr: true,
+		},
+		{
+			name:    "not bearer authorization",
+			headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			wantOk:  true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			principal, ok, err := a.authenticate(r)
+			if ok != tt.wantOk || (err != nil) != tt.wantErr {
+				t.Fatalf("authenticate() ok = %v, err = %v, want ok = %v, want err = %v", ok, err, tt.wantOk, tt.wantErr)
+			}
+			if tt.wantErr || !tt.wantOk {
+				return
+			}
+			if principal.Name != tt.wantName || !principal.HasScope(tt.wantScope) {
+				t.Errorf("authenticate() principal = %+v, want name %s with scope %s", principal, tt.wantName, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := NewAuthenticator(testAuthConfig)
+	handler := a.Middleware(slog.New(slog.NewJSONHandler(io.Discard, nil)), "/healthz", "/api/swagger/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		name       string
+		path       string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "public path", path: "/healthz", wantStatus: http.StatusOK},
+		{name: "public prefix", path: "/api/swagger/index.html", wantStatus: http.StatusOK},
+		{name: "closed path without credentials", path: "/api/v2/cars", wantStatus: http.StatusUnauthorized},
+		{name: "prefix of not prefix path", path: "/healthz/other", wantStatus: http.StatusUnauthorized},
+		{name: "closed path with api key", path: "/api/v2/cars", apiKey: "reader-key", wantStatus: http.StatusOK},
+		{name: "public path with invalid key", path: "/healthz", apiKey: "other-key", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.apiKey != "" {
+				r.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
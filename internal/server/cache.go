@@ -0,0 +1,41 @@
+package server
+
+import "net/http"
+
+// cacheControlWriter sets Cache-Control only to the successful responses,
+// so the errors arent cached
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (cw *cacheControlWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if status == http.StatusOK || status == http.StatusNotModified {
+		cw.Header().Set("Cache-Control", cw.policy)
+		// response depends on the role of the client
+		cw.Header().Add("Vary", "Authorization, X-API-Key")
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheControlWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// WithCacheControl sets Cache-Control policy of the route, empty policy means no header
+func WithCacheControl(policy string, handler http.HandlerFunc) http.HandlerFunc {
+	if policy == "" {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(&cacheControlWriter{ResponseWriter: w, policy: policy}, r)
+	}
+}
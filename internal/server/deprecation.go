@@ -0,0 +1,33 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// deprecated adds headers which notify the client that route will be removed.
+// Successor url can contain route variables like {carId}, they will be replaced with the request values
+func deprecated(handler http.HandlerFunc, successorUrl string, deprecatedAt, sunset time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deprecatedAt.IsZero() {
+			w.Header().Set("Deprecation", "true")
+		} else {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+		}
+		if !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successorUrl != "" {
+			link := successorUrl
+			for name, value := range mux.Vars(r) {
+				link = strings.ReplaceAll(link, "{"+name+"}", value)
+			}
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", link))
+		}
+		handler(w, r)
+	}
+}
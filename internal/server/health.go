@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+)
+
+const (
+	statusOk   = "ok"
+	statusFail = "fail"
+)
+
+// Checker checks that the dependency is available
+type Checker func(context.Context) error
+
+type namedChecker struct {
+	name  string
+	check Checker
+}
+
+type health struct {
+	log          *slog.Logger
+	timeout      time.Duration
+	checkers     []namedChecker
+	shuttingDown atomic.Bool
+}
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// NewHealth creates liveness and readiness handlers,
+// timeout limits the time of all dependency checks, zero value means no limit
+func NewHealth(timeout time.Duration, log *slog.Logger) *health {
+	return &health{
+		log:     log,
+		timeout: timeout,
+	}
+}
+
+// AddCheck adds dependency which is checked by the readiness handler
+func (h *health) AddCheck(name string, check Checker) {
+	h.checkers = append(h.checkers, namedChecker{name: name, check: check})
+}
+
+// ShuttingDown makes the service not ready to receive new requests
+func (h *health) ShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness responds that the process is alive
+func (h *health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: statusOk})
+}
+
+// Readiness checks all dependencies concurrently and responds 503 if any of them failed
+func (h *health) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	results := make([]checkResult, len(h.checkers))
+	wg := sync.WaitGroup{}
+	for i, checker := range h.checkers {
+		wg.Add(1)
+		go func(i int, checker namedChecker) {
+			defer wg.Done()
+			results[i] = checkResult{Status: statusOk}
+			if err := checker.check(ctx); err != nil {
+				results[i] = checkResult{Status: statusFail, Error: err.Error()}
+			}
+		}(i, checker)
+	}
+	wg.Wait()
+	resp := healthResponse{
+		Status: statusOk,
+		Checks: make(map[string]checkResult, len(results)+1),
+	}
+	shutdown := checkResult{Status: statusOk}
+	if h.shuttingDown.Load() {
+		shutdown = checkResult{Status: statusFail, Error: "service is shutting down"}
+	}
+	resp.Checks["shutdown"] = shutdown
+	for i, result := range results {
+		resp.Checks[h.checkers[i].name] = result
+	}
+	code := http.StatusOK
+	for _, result := range resp.Checks {
+		if result.Status != statusOk {
+			resp.Status = statusFail
+			code = http.StatusServiceUnavailable
+		}
+	}
+	if code != http.StatusOK {
+		l.FromContext(r.Context(), h.log).Warn("service is not ready", slog.Any("checks", resp.Checks))
+	}
+	writeHealth(w, code, resp)
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
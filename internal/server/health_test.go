@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthReadiness(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tests := []struct {
+		name         string
+		dbErr        error
+		shuttingDown bool
+		wantCode     int
+		wantFailed   string
+	}{
+		{name: "ready", wantCode: http.StatusOK},
+		{name: "database is down", dbErr: errors.New("connection refused"), wantCode: http.StatusServiceUnavailable, wantFailed: "postgres"},
+		{name: "shutting down", shuttingDown: true, wantCode: http.StatusServiceUnavailable, wantFailed: "shutdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealth(0, log)
+			h.AddCheck("postgres", func(context.Context) error { return tt.dbErr })
+			if tt.shuttingDown {
+				h.ShuttingDown()
+			}
+			rec := httptest.NewRecorder()
+			h.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var resp healthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %s", err.Error())
+			}
+			for name, result := range resp.Checks {
+				if (name == tt.wantFailed) != (result.Status == statusFail) {
+					t.Errorf("check %s has status %s", name, result.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// time to save the response after the request context is done
+	idempotencySaveTimeout = 5 * time.Second
+	// used if the max body size isnt configured
+	defaultIdempotencyBodySize = 1 << 20
+)
+
+type idempotencyStore interface {
+	ReserveIdempotencyKey(context.Context, models.IdempotencyRecord, time.Time, time.Time) (models.IdempotencyRecord, bool, error)
+	SaveIdempotencyResponse(context.Context, models.IdempotencyRecord) error
+	DeleteIdempotencyKey(context.Context, models.IdempotencyRecord) error
+	DeleteExpiredIdempotencyKeys(context.Context, time.Time) (int64, error)
+}
+
+type idempotency struct {
+	log         *slog.Logger
+	store       idempotencyStore
+	window      time.Duration
+	maxBodySize int64
+}
+
+func NewIdempotency(cfg config.IdempotencyConfig, store idempotencyStore, log *slog.Logger) *idempotency {
+	maxBodySize := cfg.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultIdempotencyBodySize
+	}
+	return &idempotency{
+		log:         log.With(slog.String("middleware", "idempotency")),
+		store:       store,
+		window:      cfg.Window,
+		maxBodySize: maxBodySize,
+	}
+}
+
+// bodyRecorder saves the response to replay it later, zero status means that nothing is written
+type bodyRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (br *bodyRecorder) WriteHeader(status int) {
+	if br.wroteHeader {
+		return
+	}
+	br.status = status
+	br.wroteHeader = true
+	br.ResponseWriter.WriteHeader(status)
+}
+
+func (br *bodyRecorder) Write(b []byte) (int, error) {
+	if !br.wroteHeader {
+		br.WriteHeader(http.StatusOK)
+	}
+	br.body.Write(b)
+	return br.ResponseWriter.Write(b)
+}
+
+// retryableStatus checks that the request failed without the result, so the key can be used again.
+// Zero status means that the handler didnt write the response
+func retryableStatus(status int) bool {
+	return status == 0 ||
+		status >= http.StatusInternalServerError ||
+		status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status == 499
+}
+
+// Handle replays the saved response for the requests with the same Idempotency-Key header.
+// Keys are separated by the authenticated principal, the same key with other body is rejected.
+// The key without response is taken over after the timeout of the route, so the key of the crashed
+// instance isnt blocked until the window ends
+func (i *idempotency) Handle(timeout time.Duration, handler http.HandlerFunc) http.HandlerFunc {
+	if i.window <= 0 {
+		return handler
+	}
+	lease := i.window
+	if timeout > 0 && timeout+idempotencySaveTimeout < i.window {
+		lease = timeout + idempotencySaveTimeout
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			handler(w, r)
+			return
+		}
+		log := l.FromContext(r.Context(), i.log)
+		if len(key) > maxIdempotencyKeyLength {
+			problem.BadRequest(w, r, "idempotency key is too long")
+			return
+		}
+		// the body is buffered to be hashed, so its size is limited
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, i.maxBodySize))
+		if err != nil {
+			if errors.As(err, new(*http.MaxBytesError)) {
+				log.Info("request body is too large", slog.Int64("limit", i.maxBodySize))
+				problem.Error(w, r, err)
+				return
+			}
+			log.Error("failed to read request body", slog.String("error", err.Error()))
+			problem.BadRequest(w, r, "error while reading request")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+		record := models.IdempotencyRecord{
+			Key:         key,
+			RequestHash: hex.EncodeToString(hash[:]),
+		}
+		if principal, ok := auth.FromContext(r.Context()); ok {
+			record.Scope = principal.Name
+		}
+		now := time.Now()
+		existing, reserved, err := i.store.ReserveIdempotencyKey(r.Context(), record, now.Add(-i.window), now.Add(-lease))
+		if err != nil {
+			log.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+			problem.Error(w, r, err)
+			return
+		}
+		if !reserved {
+			i.replay(w, r, record, existing)
+			return
+		}
+		// the reservation is identified by its time, so the request whose key is taken over doesnt change it
+		record.CreatedAt = existing.CreatedAt
+		rec := &bodyRecorder{ResponseWriter: w}
+		// the panic is handled by the recovery middleware after this one, the result of the request is unknown
+		panicked := true
+		defer func() {
+			// the response is saved even if the client is gone
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencySaveTimeout)
+			defer cancel()
+			if panicked || retryableStatus(rec.status) {
+				i.release(ctx, log, record)
+				return
+			}
+			record.Status = rec.status
+			record.ContentType = rec.Header().Get("Content-Type")
+			record.Body = rec.body.Bytes()
+			if err := i.store.SaveIdempotencyResponse(ctx, record); err != nil {
+				log.Error("failed to save idempotent response", slog.String("error", err.Error()))
+				// otherwise the retries get conflict until the key expires
+				i.release(ctx, log, record)
+			}
+		}()
+		handler(rec, r)
+		panicked = false
+	}
+}
+
+// release deletes the key, so the request can be repeated
+func (i *idempotency) release(ctx context.Context, log *slog.Logger, record models.IdempotencyRecord) {
+	if err := i.store.DeleteIdempotencyKey(ctx, record); err != nil {
+		log.Error("failed to release idempotency key", slog.String("error", err.Error()))
+	}
+}
+
+func (i *idempotency) replay(w http.ResponseWriter, r *http.Request, record, existing models.IdempotencyRecord) {
+	log := l.FromContext(r.Context(), i.log)
+	if existing.RequestHash != record.RequestHash {
+		log.Info("idempotency key is reused with other request")
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeIdempotencyMismatch,
+			"idempotency key is already used with other request body")
+		return
+	}
+	if existing.Status == 0 {
+		log.Info("request with the idempotency key is still processed")
+		problem.Write(w, r, http.StatusConflict, problem.CodeIdempotencyConflict,
+			"request with this idempotency key is still processed")
+		return
+	}
+	log.Info("replaying saved response", slog.Int("status", existing.Status))
+	if existing.ContentType != "" {
+		w.Header().Set("Content-Type", existing.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(existing.Status)
+	w.Write(existing.Body)
+}
+
+// RunCleanup periodically deletes expired keys until the context is done
+func (i *idempotency) RunCleanup(ctx context.Context) {
+	if i.window <= 0 {
+		return
+	}
+	ticker := time.NewTicker(i.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := i.store.DeleteExpiredIdempotencyKeys(ctx, time.Now().Add(-i.window))
+			if err != nil {
+				i.log.Error("failed to delete expired idempotency keys", slog.String("error", err.Error()))
+				continue
+			}
+			i.log.Debug("expired idempotency keys are deleted", slog.Int64("count", deleted))
+		}
+	}
+}
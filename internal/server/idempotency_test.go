@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+type memoryIdempotencyStore struct {
+	records map[string]models.IdempotencyRecord
+	saveErr error
+}
+
+func (m *memoryIdempotencyStore) ReserveIdempotencyKey(_ context.Context, record models.IdempotencyRecord, expiredBefore, staleBefore time.Time) (models.IdempotencyRecord, bool, error) {
+	existing, ok := m.records[record.Scope+"/"+record.Key]
+	if ok && !existing.CreatedAt.Before(expiredBefore) && (existing.Status != 0 || !existing.CreatedAt.Before(staleBefore)) {
+		return existing, false, nil
+	}
+	record.CreatedAt = time.Now()
+	m.records[record.Scope+"/"+record.Key] = record
+	return record, true, nil
+}
+
+func (m *memoryIdempotencyStore) SaveIdempotencyResponse(_ context.Context, record models.IdempotencyRecord) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	saved, ok := m.records[record.Scope+"/"+record.Key]
+	if !ok || !saved.CreatedAt.Equal(record.CreatedAt) {
+		return nil
+	}
+	saved.Status, saved.ContentType, saved.Body = record.Status, record.ContentType, record.Body
+	m.records[record.Scope+"/"+record.Key] = saved
+	return nil
+}
+
+func (m *memoryIdempotencyStore) DeleteIdempotencyKey(_ context.Context, record models.IdempotencyRecord) error {
+	if saved, ok := m.records[record.Scope+"/"+record.Key]; ok && saved.CreatedAt.Equal(record.CreatedAt) {
+		delete(m.records, record.Scope+"/"+record.Key)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyStore) DeleteExpiredIdempotencyKeys(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotencyHandle(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	store := &memoryIdempotencyStore{records: make(map[string]models.IdempotencyRecord)}
+	idem := NewIdempotency(config.IdempotencyConfig{Window: time.Hour}, store, log)
+	calls := 0
+	status := http.StatusCreated
+	handler := idem.Handle(time.Minute, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch status {
+		case 0:
+		case -1:
+			panic("handler failed")
+		default:
+			w.WriteHeader(status)
+		}
+	})
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/cars", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := send("a", `{"regNums":["X123XX150"]}`); rec.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: code = %d, calls = %d", rec.Code, calls)
+	}
+	rec := send("a", `{"regNums":["X123XX150"]}`)
+	if rec.Code != http.StatusCreated || calls != 1 || rec.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("retry: code = %d, calls = %d, replayed = %q", rec.Code, calls, rec.Header().Get(IdempotentReplayedHeader))
+	}
+	if rec := send("a", `{"regNums":["Y123YY150"]}`); rec.Code != http.StatusUnprocessableEntity || calls != 1 {
+		t.Fatalf("other body: code = %d, calls = %d", rec.Code, calls)
+	}
+
+	status = http.StatusBadGateway
+	send("b", `{}`)
+	status = http.StatusCreated
+	if rec := send("b", `{}`); rec.Code != http.StatusCreated || calls != 3 {
+		t.Fatalf("retry after failure: code = %d, calls = %d", rec.Code, calls)
+	}
+
+	status = -1
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("panic isnt passed to the recovery")
+			}
+		}()
+		send("c", `{}`)
+	}()
+	if _, ok := store.records["/c"]; ok {
+		t.Fatalf("key of the panicked request isnt released")
+	}
+
+	status = 0
+	send("d", `{}`)
+	if _, ok := store.records["/d"]; ok {
+		t.Fatalf("key of the request without response isnt released")
+	}
+
+	status = http.StatusCreated
+	store.saveErr = errors.New("db is down")
+	send("e", `{}`)
+	if _, ok := store.records["/e"]; ok {
+		t.Fatalf("key with not saved response isnt released")
+	}
+	store.saveErr = nil
+
+	// the reservation of the crashed instance
+	hash := sha256.Sum256([]byte(`{}`))
+	store.records["/f"] = models.IdempotencyRecord{Key: "f", RequestHash: hex.EncodeToString(hash[:]), CreatedAt: time.Now().Add(-30 * time.Second)}
+	if rec := send("f", `{}`); rec.Code != http.StatusConflict || calls != 6 {
+		t.Fatalf("fresh reservation: code = %d, calls = %d", rec.Code, calls)
+	}
+	store.records["/f"] = models.IdempotencyRecord{Key: "f", RequestHash: hex.EncodeToString(hash[:]), CreatedAt: time.Now().Add(-2 * time.Minute)}
+	if rec := send("f", `{}`); rec.Code != http.StatusCreated || calls != 7 {
+		t.Fatalf("stale reservation: code = %d, calls = %d", rec.Code, calls)
+	}
+}
+
+func TestIdempotencyBodyLimit(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	store := &memoryIdempotencyStore{records: make(map[string]models.IdempotencyRecord)}
+	idem := NewIdempotency(config.IdempotencyConfig{Window: time.Hour, MaxBodySize: 8}, store, log)
+	calls := 0
+	handler := idem.Handle(time.Minute, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	})
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCalls  int
+	}{
+		{name: "body in limit", body: "12345678", wantStatus: http.StatusCreated, wantCalls: 1},
+		{name: "too large body", body: "123456789", wantStatus: http.StatusRequestEntityTooLarge, wantCalls: 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/cars", strings.NewReader(tt.body))
+			req.Header.Set(IdempotencyKeyHeader, strconv.Itoa(i))
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.wantStatus || calls != tt.wantCalls {
+				t.Errorf("code = %d, calls = %d, want %d, %d", rec.Code, calls, tt.wantStatus, tt.wantCalls)
+			}
+		})
+	}
+	if _, ok := store.records["/1"]; ok {
+		t.Errorf("key of the too large request is reserved")
+	}
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/metrics"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/requestid"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware wraps handler of the server
+type Middleware func(http.Handler) http.Handler
+
+// used when request doesnt match any registered route
+const unknownRoute = "unknown"
+
+type routeCtxKey struct{}
+
+// routeInfo is filled by the router after matching the request,
+// so middlewares which are called before the router can read it after the handler is done
+type routeInfo struct {
+	template string
+}
+
+// routeFromContext returns template of the matched route
+func routeFromContext(ctx context.Context) string {
+	info, ok := ctx.Value(routeCtxKey{}).(*routeInfo)
+	if !ok || info.template == "" {
+		return unknownRoute
+	}
+	return info.template
+}
+
+// withRouteInfo puts route info to the context, must be the outer handler
+func withRouteInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), routeCtxKey{}, &routeInfo{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// captureRoute is registered in the router and saves template of the matched route
+func captureRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(routeCtxKey{}).(*routeInfo); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				info.template, _ = route.GetPathTemplate()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// responseRecorder saves status and size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.wroteHeader {
+		return
+	}
+	rr.status = status
+	rr.wroteHeader = true
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		if !rr.wroteHeader {
+			rr.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// RequestId takes request id from header or generates new one,
+// puts it to the context and to the response header
+func RequestId() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if !requestid.IsValid(id) {
+				id = requestid.New()
+			}
+			w.Header().Set(requestid.Header, id)
+			ctx := requestid.ContextWithId(r.Context(), id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Tracing continues the trace from the request headers or starts new one,
+// span is named by the route template after the handler is done
+func Tracing() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					attribute.String("request.id", requestid.FromContext(ctx)),
+				),
+			)
+			defer span.End()
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			route := routeFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(rec.status))
+			if rec.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+// RequestLogger puts request scoped logger to the context
+func RequestLogger(log *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := log.With(
+				slog.String("request_id", requestid.FromContext(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			)
+			if traceId := tracing.TraceId(r.Context()); traceId != "" {
+				reqLog = reqLog.With(slog.String("trace_id", traceId))
+			}
+			ctx := l.ContextWithLogger(r.Context(), reqLog)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AccessLog writes record about every request after it is done
+func AccessLog(log *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			// method and path are added by the request logger
+			l.FromContext(r.Context(), log).Info("access",
+				slog.String("route", routeFromContext(r.Context())),
+				slog.Int("status", rec.status),
+				slog.Int("bytes", rec.bytes),
+				slog.Duration("latency", time.Since(start)),
+			)
+		})
+	}
+}
+
+// Metrics counts requests and observes their latency per route template
+func Metrics() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			route := routeFromContext(r.Context())
+			metrics.HttpRequests.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+			metrics.HttpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// Recovery turns panic of the handler into 500 response
+func Recovery(log *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				// connection must be aborted without logging
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				l.FromContext(r.Context(), log).Error("panic while handling request",
+					slog.String("panic", fmt.Sprint(recovered)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if rec.wroteHeader {
+					return
+				}
+				problem.Write(rec, r, http.StatusInternalServerError, problem.CodeInternal, "internal server error")
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
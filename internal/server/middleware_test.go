@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/requestid"
+)
+
+func TestRequestId(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "valid id is kept", header: "abc-123", wantSame: true},
+		{name: "missing id is generated"},
+		{name: "id with space is replaced", header: "abc 123"},
+		{name: "too long id is replaced", header: strings.Repeat("a", 129)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxId string
+			handler := RequestId()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxId = requestid.FromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/cars", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			got := rec.Header().Get(requestid.Header)
+			if got != ctxId {
+				t.Errorf("response id = %s, context id = %s", got, ctxId)
+			}
+			if tt.wantSame && got != tt.header {
+				t.Errorf("id = %s, want %s", got, tt.header)
+			}
+			if !tt.wantSame && (got == tt.header || !requestid.IsValid(got)) {
+				t.Errorf("id = %s, want new valid id", got)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantStat  int
+		wantBytes int
+	}{
+		{
+			name: "written status and body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("hello"))
+			},
+			wantStat:  http.StatusCreated,
+			wantBytes: 5,
+		},
+		{
+			name:     "empty response",
+			handler:  func(w http.ResponseWriter, r *http.Request) {},
+			wantStat: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+			handler := withRouteInfo(AccessLog(log)(tt.handler))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/cars", nil))
+			var record struct {
+				Msg    string `json:"msg"`
+				Route  string `json:"route"`
+				Status int    `json:"status"`
+				Bytes  int    `json:"bytes"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("cant decode log record %q: %v", buf.String(), err)
+			}
+			if record.Msg != "access" || record.Route != unknownRoute || record.Status != tt.wantStat || record.Bytes != tt.wantBytes {
+				t.Errorf("record = %+v, want status %d and %d bytes of %s route", record, tt.wantStat, tt.wantBytes, unknownRoute)
+			}
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name: "panic before response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+		{
+			name: "panic after header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name: "without panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+			rec := httptest.NewRecorder()
+			Recovery(log)(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/cars", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantCode != "" {
+				var p problem.Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+					t.Fatalf("cant decode problem %q: %v", rec.Body.String(), err)
+				}
+				if p.Code != tt.wantCode {
+					t.Errorf("code = %s, want %s", p.Code, tt.wantCode)
+				}
+			}
+			panicked := tt.wantCode != "" || tt.wantStatus == http.StatusAccepted
+			if logged := strings.Contains(buf.String(), "panic while handling request"); logged != panicked {
+				t.Errorf("panic is logged = %v, want %v", logged, panicked)
+			}
+		})
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	rec := httptest.NewRecorder()
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("recovered = %v, want %v", recovered, http.ErrAbortHandler)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("aborted handler is logged: %s", buf.String())
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("aborted handler wrote response: %s", rec.Body.String())
+		}
+	}()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/cars", nil))
+}
+
+// writerOnly hides the flusher of the wrapped writer
+type writerOnly struct {
+	http.ResponseWriter
+}
+
+func TestResponseRecorder(t *testing.T) {
+	t.Run("first status is kept", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		rec.WriteHeader(http.StatusNotFound)
+		rec.WriteHeader(http.StatusInternalServerError)
+		rec.Write([]byte("abc"))
+		rec.Write([]byte("de"))
+		if rec.status != http.StatusNotFound || w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, written %d, want %d", rec.status, w.Code, http.StatusNotFound)
+		}
+		if rec.bytes != 5 {
+			t.Errorf("bytes = %d, want 5", rec.bytes)
+		}
+	})
+	t.Run("write sets ok status", func(t *testing.T) {
+		rec := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+		rec.Write([]byte("abc"))
+		if !rec.wroteHeader || rec.status != http.StatusOK {
+			t.Errorf("status = %d, wrote header %v, want %d", rec.status, rec.wroteHeader, http.StatusOK)
+		}
+	})
+	t.Run("flush writes header", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := &responseRecorder{ResponseWriter: w}
+		rec.Flush()
+		if !w.Flushed || !rec.wroteHeader || rec.status != http.StatusOK {
+			t.Errorf("flushed = %v, status = %d, want flushed response with %d", w.Flushed, rec.status, http.StatusOK)
+		}
+	})
+	t.Run("flush without flusher", func(t *testing.T) {
+		rec := &responseRecorder{ResponseWriter: writerOnly{httptest.NewRecorder()}}
+		rec.Flush()
+		if rec.wroteHeader {
+			t.Errorf("header is written by flush of the writer without flusher")
+		}
+	})
+	t.Run("unwrap", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := &responseRecorder{ResponseWriter: w}
+		if rec.Unwrap() != w {
+			t.Errorf("Unwrap() returned other writer")
+		}
+		if err := http.NewResponseController(rec).Flush(); err != nil || !w.Flushed {
+			t.Errorf("flush through response controller = %v, flushed %v", err, w.Flushed)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/http/problem"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/metrics"
+)
+
+// buckets which werent used for this time are removed
+const bucketIdleTime = 10 * time.Minute
+
+// bucket is the token bucket of one client on one route
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type routeLimiter struct {
+	limit   config.LimitConfig
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// take returns remaining tokens and time after which the request can be repeated,
+// zero wait means that the request is allowed
+func (rl *routeLimiter) take(key string, now time.Time) (float64, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	b := rl.refill(key, now)
+	if b.tokens < 1 {
+		return b.tokens, rl.waitTime(b.tokens)
+	}
+	b.tokens--
+	return b.tokens, 0
+}
+
+// peek returns remaining tokens and wait like take, but doesnt take the token
+func (rl *routeLimiter) peek(key string, now time.Time) (float64, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	b := rl.refill(key, now)
+	if b.tokens < 1 {
+		return b.tokens, rl.waitTime(b.tokens)
+	}
+	return b.tokens, 0
+}
+
+// refill returns the bucket of the key with the tokens added since the last request, mutex must be locked
+func (rl *routeLimiter) refill(key string, now time.Time) *bucket {
+	if now.Sub(rl.swept) > bucketIdleTime {
+		for k, b := range rl.buckets {
+			if now.Sub(b.lastSeen) > bucketIdleTime {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.swept = now
+	}
+	burst := float64(rl.limit.Burst)
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst}
+		rl.buckets[key] = b
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.lastSeen).Seconds()*rl.limit.Rate)
+	}
+	b.lastSeen = now
+	return b
+}
+
+// waitTime returns time after which the bucket will have one token
+func (rl *routeLimiter) waitTime(tokens float64) time.Duration {
+	return time.Duration((1 - tokens) / rl.limit.Rate * float64(time.Second))
+}
+
+// resetTime returns time after which the bucket will be full
+func (rl *routeLimiter) resetTime(tokens float64) time.Duration {
+	return time.Duration((float64(rl.limit.Burst) - tokens) / rl.limit.Rate * float64(time.Second))
+}
+
+type rateLimiter struct {
+	cfg config.RateLimitConfig
+	log *slog.Logger
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig, log *slog.Logger) *rateLimiter {
+	return &rateLimiter{
+		cfg: cfg,
+		log: log.With(slog.String("middleware", "rate_limit")),
+	}
+}
+
+// routeLimiter returns limiter of the route with the limit from config or the default one,
+// nil if the route isnt limited
+func (rlr *rateLimiter) routeLimiter(route string) *routeLimiter {
+	limit, ok := rlr.cfg.Routes[route]
+	if !ok {
+		limit = rlr.cfg.Default
+	}
+	if !rlr.cfg.Enabled || limit.Rate == 0 {
+		return nil
+	}
+	return &routeLimiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+}
+
+// Limit limits requests of every client to the route. It must wrap the scope check,
+// so the rejected requests are limited too
+func (rlr *rateLimiter) Limit(route string, handler http.HandlerFunc) http.HandlerFunc {
+	rl := rlr.routeLimiter(route)
+	if rl == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, wait := rl.take(rlr.clientKey(r), time.Now())
+		rlr.writeLimit(w, r, rl, route, tokens, wait)
+		if wait > 0 {
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// LimitUnauthorized limits requests which failed the authentication by the ip address before the authentication,
+// the token is taken only by the failed requests, so the clients with the valid credentials arent limited
+func (rlr *rateLimiter) LimitUnauthorized(route string) Middleware {
+	rl := rlr.routeLimiter(route)
+	return func(next http.Handler) http.Handler {
+		if rl == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := rlr.ipKey(r)
+			if tokens, wait := rl.peek(key, time.Now()); wait > 0 {
+				rlr.writeLimit(w, r, rl, route, tokens, wait)
+				return
+			}
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status == http.StatusUnauthorized {
+				rl.take(key, time.Now())
+			}
+		})
+	}
+}
+
+// writeLimit sets the headers of the limit and writes the problem if the request is limited
+func (rlr *rateLimiter) writeLimit(w http.ResponseWriter, r *http.Request, rl *routeLimiter, route string, tokens float64, wait time.Duration) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(rl.limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(tokens)))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(rl.resetTime(tokens))))
+	if wait == 0 {
+		return
+	}
+	metrics.RateLimitRejected.WithLabelValues(route).Inc()
+	l.FromContext(r.Context(), rlr.log).Info("request is rate limited", slog.String("route", route))
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+	problem.Write(w, r, http.StatusTooManyRequests, problem.CodeRateLimited, fmt.Sprintf("rate limit of route %s exceeded", route))
+}
+
+// clientKey returns name of the authenticated client or ip address
+func (rlr *rateLimiter) clientKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return "principal:" + principal.Name
+	}
+	return rlr.ipKey(r)
+}
+
+// ipKey returns ip address of the client
+func (rlr *rateLimiter) ipKey(r *http.Request) string {
+	if rlr.cfg.TrustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return "ip:" + strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+)
+
+func TestRouteLimiterTake(t *testing.T) {
+	start := time.Now()
+	rl := &routeLimiter{
+		limit:   config.LimitConfig{Rate: 2, Burst: 3},
+		buckets: make(map[string]*bucket),
+		swept:   start,
+	}
+	tests := []struct {
+		name     string
+		key      string
+		after    time.Duration
+		wantWait time.Duration
+		wantLeft int
+	}{
+		{name: "first request", key: "a", wantLeft: 2},
+		{name: "second request", key: "a", wantLeft: 1},
+		{name: "third request", key: "a", wantLeft: 0},
+		{name: "empty bucket", key: "a", wantWait: 500 * time.Millisecond},
+		{name: "other client", key: "b", wantLeft: 2},
+		{name: "refilled token", key: "a", after: 500 * time.Millisecond, wantLeft: 0},
+		{name: "refilled bucket", key: "a", after: 10 * time.Second, wantLeft: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, wait := rl.take(tt.key, start.Add(tt.after))
+			if wait != tt.wantWait || (wait == 0 && int(left) != tt.wantLeft) {
+				t.Errorf("take(%s) = %v, %v, want %v, %v", tt.key, left, wait, tt.wantLeft, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestLimitUnauthorized(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Routes:  map[string]config.LimitConfig{"auth": {Rate: 0.001, Burst: 2}},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	status := http.StatusOK
+	handler := limiter.LimitUnauthorized("auth")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	send := func(addr string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v2/cars", nil)
+		r.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	for i := 0; i < 3; i++ {
+		if code := send("10.0.0.1:1000"); code != http.StatusOK {
+			t.Fatalf("successful request %d is limited: %d", i, code)
+		}
+	}
+	status = http.StatusUnauthorized
+	for i := 0; i < 2; i++ {
+		if code := send("10.0.0.1:1000"); code != http.StatusUnauthorized {
+			t.Fatalf("failed request %d: code = %d", i, code)
+		}
+	}
+	if code := send("10.0.0.1:1001"); code != http.StatusTooManyRequests {
+		t.Errorf("request after failures: code = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := send("10.0.0.2:1000"); code != http.StatusUnauthorized {
+		t.Errorf("other ip: code = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
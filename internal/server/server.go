@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"time"
 
@@ -15,30 +16,76 @@ type server struct {
 	cfg config.HttpConfig
 	log *slog.Logger
 	router *mux.Router
+	middlewares []Middleware
+	deprecatedAt time.Time
+	sunset time.Time
 }
 
 func NewHttpServer(cfg config.HttpConfig, log *slog.Logger) *server {
+	// dates are checked while loading config
+	deprecatedAt, _ := time.Parse(time.DateOnly, cfg.LegacyRoutes.DeprecatedAt)
+	sunset, _ := time.Parse(time.DateOnly, cfg.LegacyRoutes.Sunset)
+	router := mux.NewRouter()
+	router.Use(captureRoute)
 	return &server{
 		cfg: cfg,
 		log: log,
-		router: mux.NewRouter(),
+		router: router,
+		middlewares: []Middleware{
+			RequestId(),
+			Tracing(),
+			RequestLogger(log),
+			AccessLog(log),
+			Metrics(),
+			Recovery(log),
+		},
+		deprecatedAt: deprecatedAt,
+		sunset: sunset,
 	}
 }
 
+// Use adds middlewares to the end of the chain,
+// so they are called after the default ones and before the router
+func (s *server) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// handler wraps router with the middlewares, the first middleware is the outer one
+func (s *server) handler() http.Handler {
+	var h http.Handler = s.router
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		h = s.middlewares[i](h)
+	}
+	return withRouteInfo(h)
+}
+
 func (s *server) RunServer(ctx context.Context) (errCloseCh chan error) {
 	s.log.Info("starting server")
 	errCloseCh = make(chan error)
+	// requests which are still running after the shutdown timeout are canceled via base context
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 	srv := &http.Server{
-		Handler: s.router,
+		Handler: s.handler(),
 		Addr:    fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port),
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		WriteTimeout: s.cfg.ServerWriteTimeout(),
+		ReadTimeout:  s.cfg.ServerReadTimeout(),
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 	s.log.Info("starting listening", slog.String("addres", srv.Addr))
 	go func() {
 		<-ctx.Done()
 		s.log.Info("Graceful shutdown http server")
-		errCloseCh <- srv.Shutdown(context.Background())
+		shutdownCtx := context.Background()
+		if s.cfg.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.cfg.ShutdownTimeout)
+			defer cancel()
+		}
+		err := srv.Shutdown(shutdownCtx)
+		cancelBase()
+		errCloseCh <- err
 	}()
 	go srv.ListenAndServe()
 	return
@@ -49,4 +96,13 @@ func(s *server) RegisterHandler(url string, handler http.HandlerFunc, method str
 		url,
 		handler,
 	).Methods(method)
+}
+
+// RegisterDeprecatedHandler registers handler which responses contain Deprecation, Sunset and Link headers
+func(s *server) RegisterDeprecatedHandler(url, successorUrl string, handler http.HandlerFunc, method string) {
+	s.RegisterHandler(
+		url,
+		deprecated(handler, successorUrl, s.deprecatedAt, s.sunset),
+		method,
+	)
 }
\ No newline at end of file
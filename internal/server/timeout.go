@@ -0,0 +1,19 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithTimeout sets deadline to the request context, zero timeout means no deadline
+func WithTimeout(timeout time.Duration, handler http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		handler(w, r.WithContext(ctx))
+	}
+}
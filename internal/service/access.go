@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+// names of the owner fields in the filter and sorting
+var ownerFieldNames = map[string]struct{}{
+	"owner_name":       {},
+	"owner_surname":    {},
+	"owner_patronymic": {},
+}
+
+const maskSymbols = "***"
+
+// ownerAccess returns access mode of the owner data for the principal of the request
+func (cs *carService) ownerAccess(ctx context.Context) string {
+	return principalOwnerAccess(ctx, cs.accessCfg)
+}
+
+// principalOwnerAccess returns access mode of the owner data by the role of the principal
+func principalOwnerAccess(ctx context.Context, accessCfg config.AccessConfig) string {
+	roleName := accessCfg.DefaultRole
+	if principal, ok := auth.FromContext(ctx); ok && principal.Role != "" {
+		roleName = principal.Role
+	}
+	if roleName == "" {
+		return config.OwnerAccessFull
+	}
+	role, ok := accessCfg.Roles[roleName]
+	if !ok {
+		// role from the token which isnt described in config
+		return config.OwnerAccessOmit
+	}
+	return role.Owner
+}
+
+// checkOwnerFilter forbids filtering and sorting by owner fields if owner data isnt fully available
+func checkOwnerFilter(access string, pOption models.PaginationOption, filter models.Filter) error {
+	if access == config.OwnerAccessFull {
+		return nil
+	}
+	for _, field := range filter.Fields {
+		if _, ok := ownerFieldNames[field.Name]; ok {
+			return ErrForbiddenFilter
+		}
+	}
+	for _, sortField := range pOption.Sort {
+		if _, ok := ownerFieldNames[sortField.Name]; ok {
+			return ErrForbiddenFilter
+		}
+	}
+	return nil
+}
+
+// applyOwnerAccess masks or removes owner data of the car
+func applyOwnerAccess(access string, car *models.Car) {
+	switch access {
+	case config.OwnerAccessFull:
+	case config.OwnerAccessMask:
+		if car.Owner == nil {
+			return
+		}
+		car.Owner.Name = maskValue(car.Owner.Name)
+		car.Owner.Surname = maskValue(car.Owner.Surname)
+		if car.Owner.Patronymic != nil {
+			masked := maskValue(*car.Owner.Patronymic)
+			car.Owner.Patronymic = &masked
+		}
+	default:
+		car.Owner = nil
+	}
+}
+
+// maskValue keeps only the first letter of the value
+func maskValue(value string) string {
+	if value == "" {
+		return value
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return string(r) + maskSymbols
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestPrincipalOwnerAccess(t *testing.T) {
+	accessCfg := config.AccessConfig{
+		DefaultRole: "guest",
+		Roles: map[string]config.RoleConfig{
+			"admin": {Owner: config.OwnerAccessFull},
+			"user":  {Owner: config.OwnerAccessMask},
+			"guest": {Owner: config.OwnerAccessOmit},
+		},
+	}
+	tests := []struct {
+		name      string
+		accessCfg config.AccessConfig
+		principal *auth.Principal
+		want      string
+	}{
+		{
+			name:      "full role",
+			accessCfg: accessCfg,
+			principal: &auth.Principal{Name: "a", Role: "admin"},
+			want:      config.OwnerAccessFull,
+		},
+		{
+			name:      "mask role",
+			accessCfg: accessCfg,
+			principal: &auth.Principal{Name: "u", Role: "user"},
+			want:      config.OwnerAccessMask,
+		},
+		{
+			name:      "principal without role gets default role",
+			accessCfg: accessCfg,
+			principal: &auth.Principal{Name: "n"},
+			want:      config.OwnerAccessOmit,
+		},
+		{
+			name:      "anonymous gets default role",
+			accessCfg: accessCfg,
+			want:      config.OwnerAccessOmit,
+		},
+		{
+			name:      "unconfigured role",
+			accessCfg: accessCfg,
+			principal: &auth.Principal{Name: "x", Role: "unknown"},
+			want:      config.OwnerAccessOmit,
+		},
+		{
+			name:      "no default role",
+			accessCfg: config.AccessConfig{Roles: accessCfg.Roles},
+			want:      config.OwnerAccessFull,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.ContextWithPrincipal(ctx, *tt.principal)
+			}
+			if got := principalOwnerAccess(ctx, tt.accessCfg); got != tt.want {
+				t.Errorf("principalOwnerAccess() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOwnerFilter(t *testing.T) {
+	ownerFilter := models.Filter{Fields: []models.Field{{Name: "owner_surname"}}}
+	markFilter := models.Filter{Fields: []models.Field{{Name: "mark"}}}
+	ownerSort := models.PaginationOption{Sort: []models.SortField{{Name: "owner_name", Desc: true}}}
+	yearSort := models.PaginationOption{Sort: []models.SortField{{Name: "year"}}}
+	tests := []struct {
+		name    string
+		access  string
+		option  models.PaginationOption
+		filter  models.Filter
+		wantErr bool
+	}{
+		{name: "full access filters by owner", access: config.OwnerAccessFull, option: ownerSort, filter: ownerFilter},
+		{name: "mask access filters by owner", access: config.OwnerAccessMask, filter: ownerFilter, wantErr: true},
+		{name: "omit access sorts by owner", access: config.OwnerAccessOmit, option: ownerSort, wantErr: true},
+		{name: "mask access sorts by owner", access: config.OwnerAccessMask, option: ownerSort, filter: markFilter, wantErr: true},
+		{name: "omit access without owner fields", access: config.OwnerAccessOmit, option: yearSort, filter: markFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOwnerFilter(tt.access, tt.option, tt.filter)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("checkOwnerFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrForbiddenFilter) {
+				t.Errorf("checkOwnerFilter() error = %v, want %v", err, ErrForbiddenFilter)
+			}
+		})
+	}
+}
+
+func TestApplyOwnerAccess(t *testing.T) {
+	patronymic := "Иванович"
+	maskedPatronymic := "И***"
+	tests := []struct {
+		name   string
+		access string
+		owner  *models.Owner
+		want   *models.Owner
+	}{
+		{
+			name:   "full",
+			access: config.OwnerAccessFull,
+			owner:  &models.Owner{Name: "Иван", Surname: "Петров", Patronymic: &patronymic},
+			want:   &models.Owner{Name: "Иван", Surname: "Петров", Patronymic: &patronymic},
+		},
+		{
+			name:   "mask",
+			access: config.OwnerAccessMask,
+			owner:  &models.Owner{Name: "Иван", Surname: "Петров", Patronymic: &patronymic},
+			want:   &models.Owner{Name: "И***", Surname: "П***", Patronymic: &maskedPatronymic},
+		},
+		{
+			name:   "mask without patronymic and name",
+			access: config.OwnerAccessMask,
+			owner:  &models.Owner{Surname: "Petrov"},
+			want:   &models.Owner{Surname: "P***"},
+		},
+		{
+			name:   "mask without owner",
+			access: config.OwnerAccessMask,
+		},
+		{
+			name:   "omit",
+			access: config.OwnerAccessOmit,
+			owner:  &models.Owner{Name: "Иван", Surname: "Петров"},
+		},
+		{
+			name:   "unknown access is omitted",
+			access: "unknown",
+			owner:  &models.Owner{Name: "Иван", Surname: "Петров"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car := models.Car{Id: 1, Owner: tt.owner}
+			applyOwnerAccess(tt.access, &car)
+			if !reflect.DeepEqual(car.Owner, tt.want) {
+				t.Errorf("applyOwnerAccess() owner = %+v, want %+v", car.Owner, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+)
+
+// FindCarIds returns ids of the selected cars without changing them, it is used for the preview of the batch operations
+func (cs *carService) FindCarIds(ctx context.Context, selector models.CarSelector) (_ []int, err error) {
+	ctx, span := tracing.Start(ctx, "carService.FindCarIds")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to find selected cars")
+	log.Debug("got selector", slog.Any("selector", selector))
+	access := cs.ownerAccess(ctx)
+	if err = checkOwnerFilter(access, models.PaginationOption{}, selector.Filter); err != nil {
+		log.Info("filter by owner fields is forbidden", slog.String("owner_access", access))
+		return nil, err
+	}
+	selector.Filter = cs.normalizeFilter(selector.Filter)
+	ids, err := cs.carRepo.GetCarIds(ctx, selector)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("finding cars was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Error("failed to find cars", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrGetCar, err)
+	}
+	return ids, nil
+}
+
+// BatchEditCars applies new data to the selected cars in one transaction and returns their ids.
+// The check gets ids of the locked cars before the change, its error cancels the operation
+func (cs *carService) BatchEditCars(ctx context.Context, selector models.CarSelector, newData models.CarForPatch, check func([]int) error) (_ []int, err error) {
+	ctx, span := tracing.Start(ctx, "carService.BatchEditCars")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to edit selected cars")
+	log.Debug("got selector and car data", slog.Any("selector", selector), slog.Any("car_new_data", newData))
+	access := cs.ownerAccess(ctx)
+	if err = checkOwnerFilter(access, models.PaginationOption{}, selector.Filter); err != nil {
+		log.Info("filter by owner fields is forbidden", slog.String("owner_access", access))
+		return nil, err
+	}
+	selector.Filter = cs.normalizeFilter(selector.Filter)
+	newData.Source = models.SourceManual
+	newData.UpdatedBy = editorName(ctx)
+	// the selected cars can have the different marks, so the model without the mark isnt replaced
+	cs.normalizeCar(ctx, "", newData.Mark, newData.Model)
+	ids, err := cs.carRepo.UpdateCars(ctx, selector, newData, check)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("editing cars was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Warn("failed to edit cars", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrEditCar, err)
+	}
+	log.Info("cars are edited", slog.Int("edited", len(ids)))
+	return ids, nil
+}
+
+// BatchDeleteCars deletes the selected cars in one transaction and returns their ids, the check is called as in BatchEditCars
+func (cs *carService) BatchDeleteCars(ctx context.Context, selector models.CarSelector, check func([]int) error) (_ []int, err error) {
+	ctx, span := tracing.Start(ctx, "carService.BatchDeleteCars")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to delete selected cars")
+	log.Debug("got selector", slog.Any("selector", selector))
+	access := cs.ownerAccess(ctx)
+	if err = checkOwnerFilter(access, models.PaginationOption{}, selector.Filter); err != nil {
+		log.Info("filter by owner fields is forbidden", slog.String("owner_access", access))
+		return nil, err
+	}
+	selector.Filter = cs.normalizeFilter(selector.Filter)
+	ids, err := cs.carRepo.DeleteCars(ctx, selector, check)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("deleting cars was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Warn("failed to delete cars", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrDeleteCar, err)
+	}
+	log.Info("cars are deleted", slog.Int("deleted", len(ids)))
+	return ids, nil
+}
@@ -2,104 +2,388 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
 )
 
 type carService struct {
 	log     *slog.Logger
 	carRepo carRepo
 	carInfoGetter carInfoGetter
+	carValidator carValidator
+	carNormalizer carNormalizer
+	accessCfg config.AccessConfig
 }
 
 type carRepo interface {
-	SaveCars(context.Context, []models.Car) (error)
+	SaveCars(context.Context, []models.Car, models.ConflictPolicy) (models.SaveResult, error)
 	GetCarById(context.Context, string) (models.Car, error)
+	GetCarByVin(context.Context, string) (models.Car, error)
 	GetCarsWithFilterAndPagination(context.Context, models.PaginationOption, models.Filter) ([]models.Car, error)
+	StreamCarsWithFilter(context.Context, models.PaginationOption, models.Filter, func(models.Car) error) error
 	UpdateCarById(context.Context, string, models.CarForPatch) (error)
+	ReplaceCarById(context.Context, string, models.Car) (error)
 	DeleteCarById(context.Context, string) (error)
+	GetCarIds(context.Context, models.CarSelector) ([]int, error)
+	UpdateCars(context.Context, models.CarSelector, models.CarForPatch, func([]int) error) ([]int, error)
+	DeleteCars(context.Context, models.CarSelector, func([]int) error) ([]int, error)
+	GetCarStats(context.Context, models.Filter, models.StatsOption) (models.CarStats, error)
 }
 
 type carInfoGetter func(context.Context, string) (models.Car, error)
 
-func NewCarService(logger *slog.Logger, cRepo carRepo, cGetter carInfoGetter) *carService {
+type carValidator interface {
+	ValidateCar(models.Car) []models.FieldError
+	ParsePlate(string) (models.Plate, error)
+	DecodeVin(string) (models.VinInfo, error)
+}
+
+// carNormalizer replaces marks and models by the canonical names of the catalog
+type carNormalizer interface {
+	Normalize(storedMark string, mark, model *string) []models.UnmatchedValue
+}
+
+var serviceName = slog.String("service", "car")
+
+// name of the register number in the filter
+const regNumFilterName = "reg_num"
+
+func NewCarService(logger *slog.Logger, cRepo carRepo, cGetter carInfoGetter, cValidator carValidator, cNormalizer carNormalizer, accessCfg config.AccessConfig) *carService {
 	return &carService{
-		log: logger.With(slog.String("service", "car")),
+		log: logger.With(serviceName),
 		carRepo:  cRepo,
 		carInfoGetter: cGetter,
+		carValidator: cValidator,
+		carNormalizer: cNormalizer,
+		accessCfg: accessCfg,
+	}
+}
+// logger returns request scoped logger if context contains it
+func (cs *carService) logger(ctx context.Context) *slog.Logger {
+	return l.FromContext(ctx, cs.log, serviceName)
+}
+
+// carPlate returns parsed register number, nil if it isnt russian registration plate
+func (cs *carService) carPlate(regNumber string) *models.Plate {
+	plate, err := cs.carValidator.ParsePlate(regNumber)
+	if err != nil {
+		return nil
+	}
+	return &plate
+}
+
+// plateRegNumber returns the text of the parsed plate as the register number, so the same plate typed differently
+// is saved once. Register numbers which arent russian plates are kept as is
+func (cs *carService) plateRegNumber(regNumber string) (string, *models.Plate) {
+	plate := cs.carPlate(regNumber)
+	if plate == nil {
+		return regNumber, nil
+	}
+	return plate.Text(), plate
+}
+
+// normalizeFilter replaces the plates in the register number filters by their text, so they match the saved cars.
+// Patterns arent changed, they are matched with the saved text
+func (cs *carService) normalizeFilter(filter models.Filter) models.Filter {
+	fields := make([]models.Field, len(filter.Fields))
+	for i, field := range filter.Fields {
+		if field.Name == regNumFilterName && (field.Operator == "=" || field.Operator == "<>") {
+			field.Value, _ = cs.plateRegNumber(field.Value)
+		}
+		fields[i] = field
 	}
+	filter.Fields = fields
+	return filter
 }
-func (cs *carService) AddCar(ctx context.Context, regNumbers []string) error {
+
+// normalizeCar replaces the mark and the model by the catalog names, unmatched values are kept and logged.
+// The stored mark is used for the model of the patch without the mark
+func (cs *carService) normalizeCar(ctx context.Context, storedMark string, mark, model *string) []models.UnmatchedValue {
+	unmatched := cs.carNormalizer.Normalize(storedMark, mark, model)
+	if len(unmatched) != 0 {
+		cs.logger(ctx).Info("values dont match the catalog", slog.Any("unmatched", unmatched))
+	}
+	return unmatched
+}
+
+// normalizeVin returns vin in the upper case, so the unique constraint and the lookup dont depend on the case
+func normalizeVin(vin *string) *string {
+	if vin == nil {
+		return nil
+	}
+	upper := strings.ToUpper(*vin)
+	return &upper
+}
+
+// setVinInfo decodes the vin of the car, vin info isnt stored, so it follows changes of the decoding tables
+func (cs *carService) setVinInfo(car *models.Car) {
+	if car.Vin == nil {
+		return
+	}
+	info, err := cs.carValidator.DecodeVin(*car.Vin)
+	if err != nil {
+		return
+	}
+	car.VinInfo = &info
+}
+
+// editor of the cars changed by the anonymous requests
+const anonymousEditor = "anonymous"
+
+// editorName returns name of the authenticated principal. Anonymous changes are recorded too,
+// so the editor of the previous change isnt kept with the time of the new one
+func editorName(ctx context.Context) *string {
+	name := anonymousEditor
+	if principal, ok := auth.FromContext(ctx); ok {
+		name = principal.Name
+	}
+	return &name
+}
+
+// AddCar saves the cars with the info from the external api, existing cars are skipped
+func (cs *carService) AddCar(ctx context.Context, regNumbers []string) (_ models.SaveResult, err error) {
+	ctx, span := tracing.Start(ctx, "carService.AddCar")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
 	var (
 		carList []models.Car
 		car models.Car
-		err error
 	)
-	cs.log.Info("attempt to add a car")
-	cs.log.Debug("got cars register numbers", slog.Any("register_numbers", regNumbers))
+	log.Info("attempt to add a car")
+	log.Debug("got cars register numbers", slog.Any("register_numbers", regNumbers))
 	for _, regNumber := range regNumbers {
 		car, err = cs.carInfoGetter(ctx, regNumber)
 		if err != nil {
-			cs.log.Warn("failed to get car info", slog.String("register_number", regNumber))
+			log.Warn("failed to get car info", slog.String("register_number", regNumber), slog.String("error", err.Error()))
 			break
 		}
+		// data of the external api is checked by the same rules as the data of the clients
+		if fieldErrs := cs.carValidator.ValidateCar(car); len(fieldErrs) != 0 {
+			log.Warn("got not valid car info", slog.String("register_number", regNumber), slog.Any("errors", fieldErrs))
+			return models.SaveResult{}, fmt.Errorf("%w: %s", ErrInvalidCarInfo, fieldErrs[0].Message)
+		}
+		fetchedAt := time.Now()
+		car.Source = models.SourceExternalApi
+		car.FetchedAt = &fetchedAt
+		car.UpdatedBy = editorName(ctx)
+		car.RegisterNumber, car.Plate = cs.plateRegNumber(car.RegisterNumber)
+		car.Vin = normalizeVin(car.Vin)
+		cs.normalizeCar(ctx, "", &car.Mark, &car.Model)
 		carList = append(carList, car)
 	}
 	if err != nil  {
-		return ErrGetCarInfo
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("adding cars was canceled", slog.String("error", cErr.Error()))
+			return models.SaveResult{}, cErr
+		}
+		return models.SaveResult{}, fmt.Errorf("%w: %w", ErrGetCarInfo, err)
 	}
-	cs.log.Debug("got cars info", slog.Any("cars_info", carList))
-	err = cs.carRepo.SaveCars(ctx, carList)
+	log.Debug("got cars info", slog.Any("cars_info", carList))
+	// existing cars are kept, they are added only once
+	result, err := cs.carRepo.SaveCars(ctx, carList, models.ConflictSkip)
 	if err != nil  {
-		cs.log.Error("failed to save cars", slog.String("error", err.Error()))
-		return ErrAddCar
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("adding cars was canceled", slog.String("error", cErr.Error()))
+			return models.SaveResult{}, cErr
+		}
+		log.Error("failed to save cars", slog.String("error", err.Error()))
+		return models.SaveResult{}, fmt.Errorf("%w: %w", ErrAddCar, err)
 	}
-	return nil
+	for _, pos := range result.VinConflicts {
+		log.Warn("car isnt added, its vin is used by other car", slog.String("register_number", carList[pos].RegisterNumber))
+	}
+	for _, pos := range result.Duplicates {
+		log.Info("car isnt added, its register number is repeated", slog.String("register_number", carList[pos].RegisterNumber))
+	}
+	log.Info("cars are added", slog.Any("inserted", result.Inserted), slog.Any("skipped", result.Skipped))
+	return result, nil
 }
 
-func (cs *carService) GetOneCar(ctx context.Context, carId string) (models.Car, error) {
-	cs.log.Info("attempt to get car by id")
-	cs.log.Debug("got car id", slog.String("car_id", carId))
+func (cs *carService) GetOneCar(ctx context.Context, carId string) (_ models.Car, err error) {
+	ctx, span := tracing.Start(ctx, "carService.GetOneCar")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to get car by id")
+	log.Debug("got car id", slog.String("car_id", carId))
 	car, err := cs.carRepo.GetCarById(ctx, carId)
 	if err != nil {
-		cs.log.Error("failed to get car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
-		return models.Car{}, ErrGetCar
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting car was canceled", slog.String("car_id", carId), slog.String("error", cErr.Error()))
+			return models.Car{}, cErr
+		}
+		log.Error("failed to get car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
+		return models.Car{}, fmt.Errorf("%w: %w", ErrGetCar, err)
+	}
+	applyOwnerAccess(cs.ownerAccess(ctx), &car)
+	cs.setVinInfo(&car)
+	return car, nil
+}
+
+func (cs *carService) GetCarByVin(ctx context.Context, vin string) (_ models.Car, err error) {
+	ctx, span := tracing.Start(ctx, "carService.GetCarByVin")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to get car by vin")
+	log.Debug("got vin", slog.String("vin", vin))
+	car, err := cs.carRepo.GetCarByVin(ctx, strings.ToUpper(vin))
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting car was canceled", slog.String("vin", vin), slog.String("error", cErr.Error()))
+			return models.Car{}, cErr
+		}
+		log.Error("failed to get car by vin", slog.String("vin", vin), slog.String("error", err.Error()))
+		return models.Car{}, fmt.Errorf("%w: %w", ErrGetCar, err)
 	}
+	applyOwnerAccess(cs.ownerAccess(ctx), &car)
+	cs.setVinInfo(&car)
 	return car, nil
 }
 
-func (cs *carService) GetAllCars(ctx context.Context, pOption models.PaginationOption, filter models.Filter) ([]models.Car, error) {
-	cs.log.Info("attempt to get all cars with filter")
-	cs.log.Debug("got filter and pagination options", slog.Any("pagination_option", pOption), slog.Any("filter", filter))
+func (cs *carService) GetAllCars(ctx context.Context, pOption models.PaginationOption, filter models.Filter) (_ []models.Car, err error) {
+	ctx, span := tracing.Start(ctx, "carService.GetAllCars")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to get all cars with filter")
+	log.Debug("got filter and pagination options", slog.Any("pagination_option", pOption), slog.Any("filter", filter))
+	access := cs.ownerAccess(ctx)
+	if err = checkOwnerFilter(access, pOption, filter); err != nil {
+		log.Info("filter by owner fields is forbidden", slog.String("owner_access", access))
+		return nil, err
+	}
+	filter = cs.normalizeFilter(filter)
 	carList, err := cs.carRepo.GetCarsWithFilterAndPagination(ctx, pOption, filter)
 	if err != nil {
-		cs.log.Error("failed to get cars",
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting cars was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Error("failed to get cars",
 		slog.Any("pagination_option", pOption),
 		slog.Any("filter", filter),
 		slog.String("error", err.Error()))
-		return nil, ErrGetCar
+		return nil, fmt.Errorf("%w: %w", ErrGetCar, err)
+	}
+	for i := range carList {
+		applyOwnerAccess(access, &carList[i])
+		cs.setVinInfo(&carList[i])
 	}
 	return carList, nil
 }
 
-func (cs *carService) EditCar(ctx context.Context, carId string, newData models.CarForPatch) error {
-	cs.log.Info("attempt to edit car by id")
-	cs.log.Debug("got car data", slog.String("car_id", carId), slog.Any("car_new_data", newData))
-	err := cs.carRepo.UpdateCarById(ctx, carId, newData)
+// ExportCars passes cars to the callback one by one without loading all of them
+func (cs *carService) ExportCars(ctx context.Context, pOption models.PaginationOption, filter models.Filter, callback func(models.Car) error) (err error) {
+	ctx, span := tracing.Start(ctx, "carService.ExportCars")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to export cars with filter")
+	log.Debug("got filter and pagination options", slog.Any("pagination_option", pOption), slog.Any("filter", filter))
+	access := cs.ownerAccess(ctx)
+	if err = checkOwnerFilter(access, pOption, filter); err != nil {
+		log.Info("filter by owner fields is forbidden", slog.String("owner_access", access))
+		return err
+	}
+	filter = cs.normalizeFilter(filter)
+	count := 0
+	err = cs.carRepo.StreamCarsWithFilter(ctx, pOption, filter, func(car models.Car) error {
+		applyOwnerAccess(access, &car)
+		cs.setVinInfo(&car)
+		count++
+		return callback(car)
+	})
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("exporting cars was canceled", slog.Int("exported", count), slog.String("error", cErr.Error()))
+			return cErr
+		}
+		log.Error("failed to export cars", slog.Int("exported", count), slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrGetCar, err)
+	}
+	log.Info("cars are exported", slog.Int("exported", count))
+	return nil
+}
+
+func (cs *carService) EditCar(ctx context.Context, carId string, newData models.CarForPatch) (err error) {
+	ctx, span := tracing.Start(ctx, "carService.EditCar")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to edit car by id")
+	log.Debug("got car data", slog.String("car_id", carId), slog.Any("car_new_data", newData))
+	newData.Source = models.SourceManual
+	newData.UpdatedBy = editorName(ctx)
+	if newData.RegisterNumber != nil {
+		regNumber, plate := cs.plateRegNumber(*newData.RegisterNumber)
+		newData.RegisterNumber, newData.Plate = &regNumber, plate
+	}
+	newData.Vin = normalizeVin(newData.Vin)
+	var storedMark string
+	if newData.Mark == nil && newData.Model != nil {
+		// the model is matched with the models of the car mark
+		var stored models.Car
+		stored, err = cs.carRepo.GetCarById(ctx, carId)
+		if err == nil {
+			storedMark = stored.Mark
+		}
+	}
+	if err == nil {
+		cs.normalizeCar(ctx, storedMark, newData.Mark, newData.Model)
+		err = cs.carRepo.UpdateCarById(ctx, carId, newData)
+	}
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("editing car was canceled", slog.String("car_id", carId), slog.String("error", cErr.Error()))
+			return cErr
+		}
+		log.Error("failed to edit car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrEditCar, err)
+	}
+	return nil
+}
+
+func (cs *carService) ReplaceCar(ctx context.Context, carId string, newCar models.Car) (err error) {
+	ctx, span := tracing.Start(ctx, "carService.ReplaceCar")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to replace car by id")
+	log.Debug("got car data", slog.String("car_id", carId), slog.Any("car_new_data", newCar))
+	newCar.Source = models.SourceManual
+	newCar.UpdatedBy = editorName(ctx)
+	newCar.RegisterNumber, newCar.Plate = cs.plateRegNumber(newCar.RegisterNumber)
+	newCar.Vin = normalizeVin(newCar.Vin)
+	cs.normalizeCar(ctx, "", &newCar.Mark, &newCar.Model)
+	err = cs.carRepo.ReplaceCarById(ctx, carId, newCar)
 	if err != nil {
-		cs.log.Error("failed to edit car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
-		return ErrEditCar
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("replacing car was canceled", slog.String("car_id", carId), slog.String("error", cErr.Error()))
+			return cErr
+		}
+		log.Error("failed to replace car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrReplaceCar, err)
 	}
 	return nil
 }
-func (cs *carService) DeleteCar(ctx context.Context, carId string) error {
-	cs.log.Info("attempt to delete car by id")
-	cs.log.Debug("got car id", slog.String("car_id", carId))
-	err := cs.carRepo.DeleteCarById(ctx, carId)
+
+func (cs *carService) DeleteCar(ctx context.Context, carId string) (err error) {
+	ctx, span := tracing.Start(ctx, "carService.DeleteCar")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to delete car by id")
+	log.Debug("got car id", slog.String("car_id", carId))
+	err = cs.carRepo.DeleteCarById(ctx, carId)
 	if err != nil {
-		cs.log.Error("failed to delete car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
-		return err
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("deleting car was canceled", slog.String("car_id", carId), slog.String("error", cErr.Error()))
+			return cErr
+		}
+		log.Error("failed to delete car by id", slog.String("car_id", carId), slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrDeleteCar, err)
 	}
 	return nil
 }
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+)
+
+type catalogService struct {
+	log         *slog.Logger
+	catalogRepo catalogRepo
+	// index of the loaded catalog, it is replaced after every change
+	index atomic.Pointer[catalogIndex]
+}
+
+type catalogRepo interface {
+	GetCatalog(context.Context) ([]models.CatalogMark, error)
+	// the check gets the catalog in the same transaction with the change, its error cancels the change
+	SaveCatalogMark(context.Context, models.CatalogMark, func([]models.CatalogMark) error) (models.CatalogMark, error)
+	UpdateCatalogMark(context.Context, models.CatalogMark, func([]models.CatalogMark) error) error
+	DeleteCatalogMark(context.Context, int) error
+	SaveCatalogModel(context.Context, models.CatalogModel, func([]models.CatalogMark) error) (models.CatalogModel, error)
+	UpdateCatalogModel(context.Context, models.CatalogModel, func([]models.CatalogMark) error) error
+	DeleteCatalogModel(context.Context, int) error
+	GetMarkModelCounts(context.Context) ([]models.MarkModelCount, error)
+}
+
+// catalogIndex maps the keys of the names and aliases to the canonical names
+type catalogIndex struct {
+	marks map[string]*indexedMark
+	// canonical models of all marks, used to check the model when the mark isnt known
+	models map[string][]string
+}
+
+type indexedMark struct {
+	name   string
+	models map[string]string
+}
+
+var catalogServiceName = slog.String("service", "catalog")
+
+func NewCatalogService(logger *slog.Logger, cRepo catalogRepo) *catalogService {
+	return &catalogService{
+		log:         logger.With(catalogServiceName),
+		catalogRepo: cRepo,
+	}
+}
+
+func (cs *catalogService) logger(ctx context.Context) *slog.Logger {
+	return l.FromContext(ctx, cs.log, catalogServiceName)
+}
+
+// catalogKey makes names which differ only by the case and spaces equal
+func catalogKey(value string) string {
+	return strings.ToLower(cleanCatalogName(value))
+}
+
+// cleanCatalogName trims spaces and replaces the repeated ones by one space
+func cleanCatalogName(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func newCatalogIndex(marks []models.CatalogMark) *catalogIndex {
+	index := &catalogIndex{
+		marks:  make(map[string]*indexedMark, len(marks)),
+		models: make(map[string][]string),
+	}
+	for _, mark := range marks {
+		indexed := &indexedMark{name: mark.Name, models: make(map[string]string, len(mark.Models))}
+		for _, key := range catalogKeys(mark.Name, mark.Aliases) {
+			index.marks[key] = indexed
+		}
+		for _, model := range mark.Models {
+			for _, key := range catalogKeys(model.Name, model.Aliases) {
+				indexed.models[key] = model.Name
+				if !contains(index.models[key], model.Name) {
+					index.models[key] = append(index.models[key], model.Name)
+				}
+			}
+		}
+	}
+	return index
+}
+
+func catalogKeys(name string, aliases []string) []string {
+	keys := make([]string, 0, len(aliases)+1)
+	keys = append(keys, catalogKey(name))
+	for _, alias := range aliases {
+		keys = append(keys, catalogKey(alias))
+	}
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the catalog which is used by the normalization
+func (cs *catalogService) Load(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.Load")
+	defer tracing.End(span, &err)
+	marks, err := cs.catalogRepo.GetCatalog(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGetCatalog, err)
+	}
+	cs.index.Store(newCatalogIndex(marks))
+	return nil
+}
+
+// RunRefresh periodically reloads the catalog, so the changes of the other instances are used
+func (cs *catalogService) RunRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cs.Load(ctx); err != nil {
+				cs.log.Error("failed to refresh catalog", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// reload loads the catalog after the change, the change is already saved, so the error is only logged
+func (cs *catalogService) reload(ctx context.Context) {
+	if err := cs.Load(ctx); err != nil {
+		cs.logger(ctx).Error("failed to reload catalog", slog.String("error", err.Error()))
+	}
+}
+
+// Normalize replaces the mark and the model by their canonical names and returns the values which dont match
+// the catalog. Nil values are skipped. The model without the mark is matched with the stored mark of the patched
+// car, without the stored mark it is only checked that some mark has this model. Nothing is reported while
+// the catalog is empty
+func (cs *catalogService) Normalize(storedMark string, mark, model *string) []models.UnmatchedValue {
+	if mark != nil {
+		*mark = cleanCatalogName(*mark)
+	}
+	if model != nil {
+		*model = cleanCatalogName(*model)
+	}
+	index := cs.index.Load()
+	if index == nil || len(index.marks) == 0 {
+		return nil
+	}
+	if mark == nil {
+		if model == nil {
+			return nil
+		}
+		if storedMark == "" {
+			// the model of the other mark could be taken, so it isnt replaced
+			if len(index.models[catalogKey(*model)]) == 0 {
+				return []models.UnmatchedValue{{Field: models.UnmatchedModel, Value: *model, Count: 1}}
+			}
+			return nil
+		}
+		indexed, ok := index.marks[catalogKey(storedMark)]
+		if !ok {
+			return []models.UnmatchedValue{{Field: models.UnmatchedModel, Mark: storedMark, Value: *model, Count: 1}}
+		}
+		return indexed.normalizeModel(model)
+	}
+	indexed, ok := index.marks[catalogKey(*mark)]
+	if !ok {
+		return []models.UnmatchedValue{{Field: models.UnmatchedMark, Value: *mark, Count: 1}}
+	}
+	*mark = indexed.name
+	if model == nil {
+		return nil
+	}
+	return indexed.normalizeModel(model)
+}
+
+// normalizeModel replaces the model by the canonical name of the model of this mark
+func (im *indexedMark) normalizeModel(model *string) []models.UnmatchedValue {
+	name, ok := im.models[catalogKey(*model)]
+	if !ok {
+		return []models.UnmatchedValue{{Field: models.UnmatchedModel, Mark: im.name, Value: *model, Count: 1}}
+	}
+	*model = name
+	return nil
+}
+
+// unmatchedCounter sums counts of the same unmatched values
+type unmatchedCounter struct {
+	positions map[models.UnmatchedValue]int
+	values    []models.UnmatchedValue
+}
+
+func (uc *unmatchedCounter) add(values ...models.UnmatchedValue) {
+	if uc.positions == nil {
+		uc.positions = make(map[models.UnmatchedValue]int)
+	}
+	for _, value := range values {
+		key := value
+		key.Count = 0
+		if pos, ok := uc.positions[key]; ok {
+			uc.values[pos].Count += value.Count
+			continue
+		}
+		uc.positions[key] = len(uc.values)
+		uc.values = append(uc.values, value)
+	}
+}
+
+// list returns the values sorted by the count, the most used first
+func (uc *unmatchedCounter) list() []models.UnmatchedValue {
+	list := append([]models.UnmatchedValue{}, uc.values...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Count > list[j].Count
+	})
+	return list
+}
+
+func (cs *catalogService) GetCatalog(ctx context.Context) (_ []models.CatalogMark, err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.GetCatalog")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to get catalog")
+	marks, err := cs.catalogRepo.GetCatalog(ctx)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting catalog was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Error("failed to get catalog", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrGetCatalog, err)
+	}
+	return marks, nil
+}
+
+// GetCatalogReport returns marks and models of the saved cars which dont match the catalog, the most used first
+func (cs *catalogService) GetCatalogReport(ctx context.Context) (_ []models.UnmatchedValue, err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.GetCatalogReport")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to get catalog report")
+	counts, err := cs.catalogRepo.GetMarkModelCounts(ctx)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting catalog report was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Error("failed to get cars count", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrGetCatalog, err)
+	}
+	index := cs.index.Load()
+	var unmatched unmatchedCounter
+	for _, count := range counts {
+		var indexed *indexedMark
+		if index != nil {
+			indexed = index.marks[catalogKey(count.Mark)]
+		}
+		if indexed == nil {
+			unmatched.add(models.UnmatchedValue{Field: models.UnmatchedMark, Value: count.Mark, Count: count.Count})
+			continue
+		}
+		if _, ok := indexed.models[catalogKey(count.Model)]; !ok {
+			unmatched.add(models.UnmatchedValue{Field: models.UnmatchedModel, Mark: indexed.name, Value: count.Model, Count: count.Count})
+		}
+	}
+	return unmatched.list(), nil
+}
+
+// cleanCatalogNames cleans the name and aliases, aliases which are equal to the name or to each other are removed
+func cleanCatalogNames(name *string, aliases *[]string) {
+	*name = cleanCatalogName(*name)
+	seen := map[string]struct{}{catalogKey(*name): {}}
+	cleaned := make([]string, 0, len(*aliases))
+	for _, alias := range *aliases {
+		alias = cleanCatalogName(alias)
+		if _, ok := seen[catalogKey(alias)]; ok {
+			continue
+		}
+		seen[catalogKey(alias)] = struct{}{}
+		cleaned = append(cleaned, alias)
+	}
+	*aliases = cleaned
+}
+
+// checkCatalogNames checks that the name and aliases arent used by the other entries
+func checkCatalogNames(name string, aliases []string, used map[string]string) error {
+	for _, key := range catalogKeys(name, aliases) {
+		if other, ok := used[key]; ok {
+			return fmt.Errorf("%w: %s is used by %s", ErrCatalogConflict, key, other)
+		}
+	}
+	return nil
+}
+
+// usedMarkNames returns names of the marks by the keys of their names and aliases, the mark with the id is skipped
+func usedMarkNames(marks []models.CatalogMark, skipId int) map[string]string {
+	used := make(map[string]string)
+	for _, mark := range marks {
+		if mark.Id == skipId {
+			continue
+		}
+		for _, key := range catalogKeys(mark.Name, mark.Aliases) {
+			used[key] = mark.Name
+		}
+	}
+	return used
+}
+
+// usedModelNames returns names of the models of the mark like usedMarkNames, ok is false if the mark doesnt exist
+func usedModelNames(marks []models.CatalogMark, markId, skipId int) (map[string]string, bool) {
+	for _, mark := range marks {
+		if mark.Id != markId {
+			continue
+		}
+		used := make(map[string]string)
+		for _, model := range mark.Models {
+			if model.Id == skipId {
+				continue
+			}
+			for _, key := range catalogKeys(model.Name, model.Aliases) {
+				used[key] = model.Name
+			}
+		}
+		return used, true
+	}
+	return nil, false
+}
+
+// findModel returns the model with the id
+func findModel(marks []models.CatalogMark, modelId int) (models.CatalogModel, bool) {
+	for _, mark := range marks {
+		for _, model := range mark.Models {
+			if model.Id == modelId {
+				return model, true
+			}
+		}
+	}
+	return models.CatalogModel{}, false
+}
+
+// changeCatalog saves the change and reloads the catalog, the errors are logged with the operation name
+func (cs *catalogService) changeCatalog(ctx context.Context, operation string, change func() error) error {
+	log := cs.logger(ctx)
+	log.Info("attempt to " + operation)
+	if err := change(); err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info(operation+" was canceled", slog.String("error", cErr.Error()))
+			return cErr
+		}
+		log.Warn("failed to "+operation, slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrEditCatalog, err)
+	}
+	cs.reload(ctx)
+	return nil
+}
+
+func (cs *catalogService) AddMark(ctx context.Context, mark models.CatalogMark) (_ models.CatalogMark, err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.AddMark")
+	defer tracing.End(span, &err)
+	cleanCatalogNames(&mark.Name, &mark.Aliases)
+	err = cs.changeCatalog(ctx, "add catalog mark", func() error {
+		saved, err := cs.catalogRepo.SaveCatalogMark(ctx, mark, func(marks []models.CatalogMark) error {
+			return checkCatalogNames(mark.Name, mark.Aliases, usedMarkNames(marks, 0))
+		})
+		mark = saved
+		return err
+	})
+	if err != nil {
+		return models.CatalogMark{}, err
+	}
+	mark.Models = []models.CatalogModel{}
+	return mark, nil
+}
+
+func (cs *catalogService) EditMark(ctx context.Context, mark models.CatalogMark) (err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.EditMark")
+	defer tracing.End(span, &err)
+	cleanCatalogNames(&mark.Name, &mark.Aliases)
+	return cs.changeCatalog(ctx, "edit catalog mark", func() error {
+		return cs.catalogRepo.UpdateCatalogMark(ctx, mark, func(marks []models.CatalogMark) error {
+			return checkCatalogNames(mark.Name, mark.Aliases, usedMarkNames(marks, mark.Id))
+		})
+	})
+}
+
+func (cs *catalogService) DeleteMark(ctx context.Context, markId int) (err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.DeleteMark")
+	defer tracing.End(span, &err)
+	return cs.changeCatalog(ctx, "delete catalog mark", func() error {
+		return cs.catalogRepo.DeleteCatalogMark(ctx, markId)
+	})
+}
+
+func (cs *catalogService) AddModel(ctx context.Context, model models.CatalogModel) (_ models.CatalogModel, err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.AddModel")
+	defer tracing.End(span, &err)
+	cleanCatalogNames(&model.Name, &model.Aliases)
+	err = cs.changeCatalog(ctx, "add catalog model", func() error {
+		saved, err := cs.catalogRepo.SaveCatalogModel(ctx, model, func(marks []models.CatalogMark) error {
+			// missing mark is reported by the storage
+			used, _ := usedModelNames(marks, model.MarkId, 0)
+			return checkCatalogNames(model.Name, model.Aliases, used)
+		})
+		model = saved
+		return err
+	})
+	if err != nil {
+		return models.CatalogModel{}, err
+	}
+	return model, nil
+}
+
+// EditModel changes name and aliases of the model, its mark is kept
+func (cs *catalogService) EditModel(ctx context.Context, model models.CatalogModel) (err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.EditModel")
+	defer tracing.End(span, &err)
+	cleanCatalogNames(&model.Name, &model.Aliases)
+	return cs.changeCatalog(ctx, "edit catalog model", func() error {
+		return cs.catalogRepo.UpdateCatalogModel(ctx, model, func(marks []models.CatalogMark) error {
+			// missing model is reported by the storage
+			var used map[string]string
+			if existing, ok := findModel(marks, model.Id); ok {
+				used, _ = usedModelNames(marks, existing.MarkId, model.Id)
+			}
+			return checkCatalogNames(model.Name, model.Aliases, used)
+		})
+	})
+}
+
+func (cs *catalogService) DeleteModel(ctx context.Context, modelId int) (err error) {
+	ctx, span := tracing.Start(ctx, "catalogService.DeleteModel")
+	defer tracing.End(span, &err)
+	return cs.changeCatalog(ctx, "delete catalog model", func() error {
+		return cs.catalogRepo.DeleteCatalogModel(ctx, modelId)
+	})
+}
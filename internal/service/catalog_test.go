@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+var testCatalog = []models.CatalogMark{
+	{
+		Name:    "Lada",
+		Aliases: []string{"ВАЗ", "Жигули"},
+		Models: []models.CatalogModel{
+			{Name: "Vesta", Aliases: []string{"Веста"}},
+			{Name: "Granta"},
+		},
+	},
+	{
+		Name: "Kia",
+		Models: []models.CatalogModel{
+			{Name: "Rio", Aliases: []string{"Рио"}},
+			{Name: "Granta"},
+		},
+	},
+	{
+		Name:    "Land Rover",
+		Aliases: []string{"LR"},
+		Models:  []models.CatalogModel{{Name: "Range Rover Sport"}},
+	},
+}
+
+type memoryCatalogRepo struct {
+	marks  []models.CatalogMark
+	counts []models.MarkModelCount
+	saved  []models.CatalogMark
+}
+
+func (m *memoryCatalogRepo) GetCatalog(context.Context) ([]models.CatalogMark, error) {
+	return m.marks, nil
+}
+
+func (m *memoryCatalogRepo) SaveCatalogMark(_ context.Context, mark models.CatalogMark, check func([]models.CatalogMark) error) (models.CatalogMark, error) {
+	if err := check(m.marks); err != nil {
+		return models.CatalogMark{}, err
+	}
+	mark.Id = len(m.marks) + 1
+	m.saved = append(m.saved, mark)
+	return mark, nil
+}
+
+func (m *memoryCatalogRepo) UpdateCatalogMark(_ context.Context, mark models.CatalogMark, check func([]models.CatalogMark) error) error {
+	return check(m.marks)
+}
+
+func (m *memoryCatalogRepo) DeleteCatalogMark(context.Context, int) error {
+	return nil
+}
+
+func (m *memoryCatalogRepo) SaveCatalogModel(_ context.Context, model models.CatalogModel, check func([]models.CatalogMark) error) (models.CatalogModel, error) {
+	return model, check(m.marks)
+}
+
+func (m *memoryCatalogRepo) UpdateCatalogModel(_ context.Context, model models.CatalogModel, check func([]models.CatalogMark) error) error {
+	return check(m.marks)
+}
+
+func (m *memoryCatalogRepo) DeleteCatalogModel(context.Context, int) error {
+	return nil
+}
+
+func (m *memoryCatalogRepo) GetMarkModelCounts(context.Context) ([]models.MarkModelCount, error) {
+	return m.counts, nil
+}
+
+// newTestCatalog returns the catalog with ids, the models of the mark have ids from mark id * 10
+func newTestCatalog() []models.CatalogMark {
+	marks := make([]models.CatalogMark, len(testCatalog))
+	for i, mark := range testCatalog {
+		mark.Id = i + 1
+		mark.Models = append([]models.CatalogModel{}, mark.Models...)
+		for j := range mark.Models {
+			mark.Models[j].Id = mark.Id*10 + j
+			mark.Models[j].MarkId = mark.Id
+		}
+		marks[i] = mark
+	}
+	return marks
+}
+
+func TestNewCatalogIndex(t *testing.T) {
+	index := newCatalogIndex(testCatalog)
+	tests := []struct {
+		name      string
+		mark      string
+		wantMark  string
+		model     string
+		wantModel string
+	}{
+		{name: "name", mark: "lada", wantMark: "Lada", model: "vesta", wantModel: "Vesta"},
+		{name: "mark alias", mark: "ваз", wantMark: "Lada", model: "granta", wantModel: "Granta"},
+		{name: "model alias", mark: "kia", wantMark: "Kia", model: "рио", wantModel: "Rio"},
+		{name: "spaces", mark: "land rover", wantMark: "Land Rover", model: "range rover sport", wantModel: "Range Rover Sport"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexed, ok := index.marks[tt.mark]
+			if !ok {
+				t.Fatalf("mark %q isnt indexed", tt.mark)
+			}
+			if indexed.name != tt.wantMark {
+				t.Errorf("mark = %q, want %q", indexed.name, tt.wantMark)
+			}
+			if got := indexed.models[tt.model]; got != tt.wantModel {
+				t.Errorf("model = %q, want %q", got, tt.wantModel)
+			}
+		})
+	}
+	if got, want := index.models["granta"], []string{"Granta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("models of all marks = %v, want %v", got, want)
+	}
+	if got, want := index.models["rio"], []string{"Rio"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("models of all marks = %v, want %v", got, want)
+	}
+	if _, ok := index.marks["kia"].models["vesta"]; ok {
+		t.Error("model of the other mark is indexed")
+	}
+}
+
+func TestCatalogNormalize(t *testing.T) {
+	cs := NewCatalogService(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	cs.index.Store(newCatalogIndex(testCatalog))
+	str := func(s string) *string {
+		return &s
+	}
+	tests := []struct {
+		name          string
+		storedMark    string
+		mark          *string
+		model         *string
+		wantMark      *string
+		wantModel     *string
+		wantUnmatched []models.UnmatchedValue
+	}{
+		{
+			name:      "case",
+			mark:      str("LADA"),
+			model:     str("vEsTa"),
+			wantMark:  str("Lada"),
+			wantModel: str("Vesta"),
+		},
+		{
+			name:      "spaces",
+			mark:      str("  land   rover "),
+			model:     str("range  rover sport"),
+			wantMark:  str("Land Rover"),
+			wantModel: str("Range Rover Sport"),
+		},
+		{
+			name:      "aliases",
+			mark:      str("Жигули"),
+			model:     str("веста"),
+			wantMark:  str("Lada"),
+			wantModel: str("Vesta"),
+		},
+		{
+			name:          "unknown mark",
+			mark:          str(" Tesla "),
+			model:         str("Model 3"),
+			wantMark:      str("Tesla"),
+			wantModel:     str("Model 3"),
+			wantUnmatched: []models.UnmatchedValue{{Field: models.UnmatchedMark, Value: "Tesla", Count: 1}},
+		},
+		{
+			name:          "model of the other mark",
+			mark:          str("kia"),
+			model:         str("Vesta"),
+			wantMark:      str("Kia"),
+			wantModel:     str("Vesta"),
+			wantUnmatched: []models.UnmatchedValue{{Field: models.UnmatchedModel, Mark: "Kia", Value: "Vesta", Count: 1}},
+		},
+		{
+			name:     "mark only",
+			mark:     str("lr"),
+			wantMark: str("Land Rover"),
+		},
+		{
+			name:       "model patch with the stored mark",
+			storedMark: "Kia",
+			model:      str("рио"),
+			wantModel:  str("Rio"),
+		},
+		{
+			name:          "model patch of the other mark",
+			storedMark:    "Kia",
+			model:         str("vesta"),
+			wantModel:     str("vesta"),
+			wantUnmatched: []models.UnmatchedValue{{Field: models.UnmatchedModel, Mark: "Kia", Value: "vesta", Count: 1}},
+		},
+		{
+			name:          "model patch with the unknown stored mark",
+			storedMark:    "Tesla",
+			model:         str("Model 3"),
+			wantModel:     str("Model 3"),
+			wantUnmatched: []models.UnmatchedValue{{Field: models.UnmatchedModel, Mark: "Tesla", Value: "Model 3", Count: 1}},
+		},
+		{
+			name:      "model patch without the stored mark isnt replaced",
+			model:     str(" веста "),
+			wantModel: str("веста"),
+		},
+		{
+			name:          "unknown model patch without the stored mark",
+			model:         str("Model 3"),
+			wantModel:     str("Model 3"),
+			wantUnmatched: []models.UnmatchedValue{{Field: models.UnmatchedModel, Value: "Model 3", Count: 1}},
+		},
+		{
+			name: "nothing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cs.Normalize(tt.storedMark, tt.mark, tt.model)
+			if !reflect.DeepEqual(got, tt.wantUnmatched) {
+				t.Errorf("Normalize() = %v, want %v", got, tt.wantUnmatched)
+			}
+			if !reflect.DeepEqual(tt.mark, tt.wantMark) {
+				t.Errorf("mark = %v, want %v", deref(tt.mark), deref(tt.wantMark))
+			}
+			if !reflect.DeepEqual(tt.model, tt.wantModel) {
+				t.Errorf("model = %v, want %v", deref(tt.model), deref(tt.wantModel))
+			}
+		})
+	}
+}
+
+func TestCatalogNormalizeEmpty(t *testing.T) {
+	cs := NewCatalogService(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	for _, index := range []*catalogIndex{nil, newCatalogIndex(nil)} {
+		if index != nil {
+			cs.index.Store(index)
+		}
+		mark, model := " Tesla ", "Model  3"
+		if got := cs.Normalize("", &mark, &model); got != nil {
+			t.Errorf("Normalize() = %v, want nil", got)
+		}
+		if mark != "Tesla" || model != "Model 3" {
+			t.Errorf("Normalize() cleaned values to %q %q, want %q %q", mark, model, "Tesla", "Model 3")
+		}
+	}
+}
+
+func TestCatalogEdit(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(context.Context, *catalogService) error
+		conflict bool
+	}{
+		{
+			name: "new mark",
+			change: func(ctx context.Context, cs *catalogService) error {
+				_, err := cs.AddMark(ctx, models.CatalogMark{Name: "Tesla", Aliases: []string{"Тесла"}})
+				return err
+			},
+		},
+		{
+			name: "mark with used alias",
+			change: func(ctx context.Context, cs *catalogService) error {
+				_, err := cs.AddMark(ctx, models.CatalogMark{Name: "Tesla", Aliases: []string{" жигули "}})
+				return err
+			},
+			conflict: true,
+		},
+		{
+			name: "mark keeps its names",
+			change: func(ctx context.Context, cs *catalogService) error {
+				return cs.EditMark(ctx, models.CatalogMark{Id: 1, Name: "LADA", Aliases: []string{"ВАЗ"}})
+			},
+		},
+		{
+			name: "mark takes other mark name",
+			change: func(ctx context.Context, cs *catalogService) error {
+				return cs.EditMark(ctx, models.CatalogMark{Id: 1, Name: "Lada", Aliases: []string{"kia"}})
+			},
+			conflict: true,
+		},
+		{
+			name: "model of other mark",
+			change: func(ctx context.Context, cs *catalogService) error {
+				_, err := cs.AddModel(ctx, models.CatalogModel{MarkId: 2, Name: "Vesta"})
+				return err
+			},
+		},
+		{
+			name: "model with used name",
+			change: func(ctx context.Context, cs *catalogService) error {
+				_, err := cs.AddModel(ctx, models.CatalogModel{MarkId: 1, Name: "GRANTA"})
+				return err
+			},
+			conflict: true,
+		},
+		{
+			name: "model takes alias of other model",
+			change: func(ctx context.Context, cs *catalogService) error {
+				return cs.EditModel(ctx, models.CatalogModel{Id: 11, Name: "Granta", Aliases: []string{"веста"}})
+			},
+			conflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := NewCatalogService(slog.New(slog.NewTextHandler(io.Discard, nil)), &memoryCatalogRepo{marks: newTestCatalog()})
+			err := tt.change(context.Background(), cs)
+			if tt.conflict {
+				if !errors.Is(err, ErrCatalogConflict) || !errors.Is(err, ErrEditCatalog) {
+					t.Fatalf("error = %v, want %v", err, ErrCatalogConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCatalogAddMarkCleansNames(t *testing.T) {
+	repo := &memoryCatalogRepo{}
+	cs := NewCatalogService(slog.New(slog.NewTextHandler(io.Discard, nil)), repo)
+	mark, err := cs.AddMark(context.Background(), models.CatalogMark{Name: " Land  Rover ", Aliases: []string{"land rover", " LR", "lr "}})
+	if err != nil {
+		t.Fatalf("AddMark() error = %v", err)
+	}
+	want := models.CatalogMark{Id: 1, Name: "Land Rover", Aliases: []string{"LR"}, Models: []models.CatalogModel{}}
+	if !reflect.DeepEqual(mark, want) {
+		t.Errorf("AddMark() = %+v, want %+v", mark, want)
+	}
+	if len(repo.saved) != 1 || repo.saved[0].Name != want.Name {
+		t.Errorf("saved marks = %+v", repo.saved)
+	}
+}
+
+func TestCatalogReport(t *testing.T) {
+	repo := &memoryCatalogRepo{
+		marks: newTestCatalog(),
+		counts: []models.MarkModelCount{
+			{Mark: "Lada", Model: "Vesta", Count: 10},
+			{Mark: "lada", Model: "2107", Count: 2},
+			{Mark: "LADA", Model: "2107", Count: 3},
+			{Mark: "Tesla", Model: "Model 3", Count: 4},
+			{Mark: "Kia", Model: "Рио", Count: 1},
+		},
+	}
+	cs := NewCatalogService(slog.New(slog.NewTextHandler(io.Discard, nil)), repo)
+	ctx := context.Background()
+	if err := cs.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got, err := cs.GetCatalogReport(ctx)
+	if err != nil {
+		t.Fatalf("GetCatalogReport() error = %v", err)
+	}
+	want := []models.UnmatchedValue{
+		{Field: models.UnmatchedModel, Mark: "Lada", Value: "2107", Count: 5},
+		{Field: models.UnmatchedMark, Value: "Tesla", Count: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetCatalogReport() = %v, want %v", got, want)
+	}
+}
+
+func deref(value *string) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
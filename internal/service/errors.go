@@ -1,11 +1,37 @@
 package service
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrGetCarInfo = errors.New("car with this register number didnt find")
+	ErrInvalidCarInfo = errors.New("got not valid car info")
 	ErrAddCar = errors.New("failed to save cars")
 	ErrGetCar = errors.New("failed to get car")
 	ErrEditCar = errors.New("failed to edit car")
+	ErrReplaceCar = errors.New("failed to replace car")
 	ErrDeleteCar = errors.New("failed to delete car")
-)
\ No newline at end of file
+	ErrGetStats = errors.New("failed to get cars stats")
+	ErrGetSuggestions = errors.New("failed to get suggestions")
+	ErrImportCars = errors.New("failed to import cars")
+	ErrImportFile = errors.New("failed to read imported file")
+	ErrGetCatalog = errors.New("failed to get catalog")
+	ErrEditCatalog = errors.New("failed to edit catalog")
+	ErrCatalogConflict = errors.New("name or alias is already used by other catalog entry")
+
+	ErrCanceled = errors.New("operation was canceled")
+
+	ErrForbiddenFilter = errors.New("filtering by owner fields is not allowed")
+	ErrForbiddenSuggest = errors.New("suggestions of owner fields are not allowed")
+)
+
+// canceledErr returns ErrCanceled if context of the operation is done
+func canceledErr(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrCanceled, context.Cause(ctx))
+}
\ No newline at end of file
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/importer"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+)
+
+// number of the cars saved by one query
+const importBatchSize = 500
+
+// ImportCars saves valid rows by batches and collects errors of the invalid ones, existing cars are
+// resolved by the policy. Nothing is saved in dry run mode. Batches which are saved before the error arent rolled back,
+// so the report is returned with the error and contains the line where the import is stopped
+func (cs *carService) ImportCars(ctx context.Context, rows importer.RowReader, dryRun bool, policy models.ConflictPolicy) (_ models.ImportReport, err error) {
+	ctx, span := tracing.Start(ctx, "carService.ImportCars")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to import cars", slog.Bool("dry_run", dryRun), slog.String("on_conflict", string(policy)))
+	report := models.ImportReport{
+		DryRun:     dryRun,
+		OnConflict: policy,
+		Errors:     []models.ImportRowError{},
+		Unmatched:  []models.UnmatchedValue{},
+	}
+	editor := editorName(ctx)
+	// the same register number and vin can be imported only once
+	seen := make(map[string]int)
+	seenVins := make(map[string]int)
+	var unmatched unmatchedCounter
+	batch := make([]models.Car, 0, importBatchSize)
+	// lines of the rows of the batch
+	batchLines := make([]int, 0, importBatchSize)
+	lastLine := 0
+	save := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if !dryRun {
+			result, err := cs.carRepo.SaveCars(ctx, batch, policy)
+			if err != nil {
+				return err
+			}
+			report.Inserted += len(result.Inserted)
+			report.Updated += len(result.Updated)
+			report.Skipped += len(result.Skipped)
+			report.Saved = report.Inserted + report.Updated
+			for _, pos := range result.VinConflicts {
+				report.Valid--
+				report.Invalid++
+				report.Errors = append(report.Errors, models.ImportRowError{
+					Line:           batchLines[pos],
+					RegisterNumber: batch[pos].RegisterNumber,
+					Errors: []models.FieldError{{
+						Field:   "vin",
+						Rule:    models.RuleUnique,
+						Message: "vin is used by other car",
+					}},
+				})
+			}
+		}
+		batch = batch[:0]
+		batchLines = batchLines[:0]
+		return nil
+	}
+	var row models.ImportRow
+	for {
+		row, err = rows.Next()
+		if errors.Is(err, io.EOF) {
+			err = save()
+			break
+		}
+		if err != nil {
+			report.StoppedAtLine = stoppedAtLine(batchLines, lastLine)
+			report.Unmatched = unmatched.list()
+			log.Warn("failed to read imported file", slog.Int("saved", report.Saved), slog.Int("stopped_at_line", report.StoppedAtLine),
+				slog.String("error", err.Error()))
+			return report, fmt.Errorf("%w: %w", ErrImportFile, err)
+		}
+		report.Total++
+		lastLine = row.Line
+		if len(row.Errors) == 0 {
+			// the same plate typed differently is the same car
+			row.Car.RegisterNumber, row.Car.Plate = cs.plateRegNumber(row.Car.RegisterNumber)
+			if line, ok := seen[row.Car.RegisterNumber]; ok {
+				row.Errors = []models.FieldError{{
+					Field:   "regNum",
+					Rule:    models.RuleUnique,
+					Message: fmt.Sprintf("register number is already imported from line %d", line),
+				}}
+			} else if row.Car.Vin != nil {
+				row.Car.Vin = normalizeVin(row.Car.Vin)
+				if line, ok := seenVins[*row.Car.Vin]; ok {
+					row.Errors = []models.FieldError{{
+						Field:   "vin",
+						Rule:    models.RuleUnique,
+						Message: fmt.Sprintf("vin is already imported from line %d", line),
+					}}
+				}
+			}
+		}
+		if len(row.Errors) != 0 {
+			report.Invalid++
+			report.Errors = append(report.Errors, models.ImportRowError{
+				Line:           row.Line,
+				RegisterNumber: row.Car.RegisterNumber,
+				Errors:         row.Errors,
+			})
+			continue
+		}
+		seen[row.Car.RegisterNumber] = row.Line
+		if row.Car.Vin != nil {
+			seenVins[*row.Car.Vin] = row.Line
+		}
+		report.Valid++
+		car := row.Car
+		car.Source = models.SourceImport
+		car.FetchedAt = nil
+		car.UpdatedBy = editor
+		unmatched.add(cs.carNormalizer.Normalize("", &car.Mark, &car.Model)...)
+		batch = append(batch, car)
+		batchLines = append(batchLines, row.Line)
+		if len(batch) == importBatchSize {
+			if err = save(); err != nil {
+				break
+			}
+		}
+	}
+	report.Unmatched = unmatched.list()
+	if err != nil {
+		report.StoppedAtLine = stoppedAtLine(batchLines, lastLine)
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("importing cars was canceled", slog.Int("saved", report.Saved), slog.String("error", cErr.Error()))
+			return report, cErr
+		}
+		log.Error("failed to import cars", slog.Int("saved", report.Saved), slog.String("error", err.Error()))
+		return report, fmt.Errorf("%w: %w", ErrImportCars, err)
+	}
+	log.Info("cars are imported",
+		slog.Int("total", report.Total),
+		slog.Int("invalid", report.Invalid),
+		slog.Int("inserted", report.Inserted),
+		slog.Int("updated", report.Updated),
+		slog.Int("skipped", report.Skipped))
+	return report, nil
+}
+
+// stoppedAtLine returns the line of the first row of the not saved batch, or the line after the last read row
+func stoppedAtLine(batchLines []int, lastLine int) int {
+	if len(batchLines) != 0 {
+		return batchLines[0]
+	}
+	return lastLine + 1
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+)
+
+// GetCarStats returns the number, aggregates and facets of the cars selected by the filter
+func (cs *carService) GetCarStats(ctx context.Context, filter models.Filter, option models.StatsOption) (_ models.CarStats, err error) {
+	ctx, span := tracing.Start(ctx, "carService.GetCarStats")
+	defer tracing.End(span, &err)
+	log := cs.logger(ctx)
+	log.Info("attempt to get cars stats")
+	log.Debug("got filter and stats options", slog.Any("filter", filter), slog.Any("stats_option", option))
+	access := cs.ownerAccess(ctx)
+	if err = checkOwnerFilter(access, models.PaginationOption{}, filter); err != nil {
+		log.Info("filter by owner fields is forbidden", slog.String("owner_access", access))
+		return models.CarStats{}, err
+	}
+	filter = cs.normalizeFilter(filter)
+	stats, err := cs.carRepo.GetCarStats(ctx, filter, option)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting cars stats was canceled", slog.String("error", cErr.Error()))
+			return models.CarStats{}, cErr
+		}
+		log.Error("failed to get cars stats",
+			slog.Any("filter", filter),
+			slog.Any("stats_option", option),
+			slog.String("error", err.Error()))
+		return models.CarStats{}, fmt.Errorf("%w: %w", ErrGetStats, err)
+	}
+	return stats, nil
+}
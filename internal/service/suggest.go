@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	l "github.com/EwvwGeN/EffectiveMobile_assignment/internal/logger"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+)
+
+// max number of the cached queries, the cache is cleared when it is full
+const maxCachedSuggestions = 1000
+
+type suggestService struct {
+	log         *slog.Logger
+	suggestRepo suggestRepo
+	cacheTtl    time.Duration
+	accessCfg   config.AccessConfig
+
+	mu    sync.Mutex
+	cache map[models.SuggestQuery]cachedSuggestions
+}
+
+type suggestRepo interface {
+	GetSuggestions(context.Context, models.SuggestQuery) ([]models.Suggestion, error)
+	RefreshSuggestions(context.Context) error
+}
+
+type cachedSuggestions struct {
+	suggestions []models.Suggestion
+	expiresAt   time.Time
+}
+
+var suggestServiceName = slog.String("service", "suggest")
+
+func NewSuggestService(logger *slog.Logger, sRepo suggestRepo, suggestCfg config.SuggestConfig, accessCfg config.AccessConfig) *suggestService {
+	return &suggestService{
+		log:         logger.With(suggestServiceName),
+		suggestRepo: sRepo,
+		cacheTtl:    suggestCfg.CacheTtl,
+		accessCfg:   accessCfg,
+		cache:       make(map[models.SuggestQuery]cachedSuggestions),
+	}
+}
+
+func (ss *suggestService) logger(ctx context.Context) *slog.Logger {
+	return l.FromContext(ctx, ss.log, suggestServiceName)
+}
+
+// Suggest returns the most used values of the field which start with the prefix. The same queries are answered
+// from the cache during its ttl, owner fields are suggested only with the full access to the owner data
+func (ss *suggestService) Suggest(ctx context.Context, query models.SuggestQuery) (_ []models.Suggestion, err error) {
+	ctx, span := tracing.Start(ctx, "suggestService.Suggest")
+	defer tracing.End(span, &err)
+	log := ss.logger(ctx)
+	log.Info("attempt to get suggestions")
+	log.Debug("got suggest query", slog.Any("query", query))
+	if query.Field == models.SuggestOwnerName || query.Field == models.SuggestOwnerSurname {
+		if access := principalOwnerAccess(ctx, ss.accessCfg); access != config.OwnerAccessFull {
+			log.Info("suggestions of owner fields are forbidden", slog.String("owner_access", access))
+			return nil, ErrForbiddenSuggest
+		}
+	}
+	// the case is ignored by the search, so the queries with the different case are the same
+	query.Prefix = strings.ToLower(query.Prefix)
+	query.Mark = strings.ToLower(query.Mark)
+	if suggestions, ok := ss.cached(query); ok {
+		log.Debug("suggestions are found in cache")
+		return suggestions, nil
+	}
+	suggestions, err := ss.suggestRepo.GetSuggestions(ctx, query)
+	if err != nil {
+		if cErr := canceledErr(ctx); cErr != nil {
+			log.Info("getting suggestions was canceled", slog.String("error", cErr.Error()))
+			return nil, cErr
+		}
+		log.Error("failed to get suggestions", slog.Any("query", query), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrGetSuggestions, err)
+	}
+	ss.store(query, suggestions)
+	return suggestions, nil
+}
+
+func (ss *suggestService) cached(query models.SuggestQuery) ([]models.Suggestion, bool) {
+	if ss.cacheTtl <= 0 {
+		return nil, false
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	entry, ok := ss.cache[query]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.suggestions, true
+}
+
+// store caches the suggestions, the expired entries are removed when the cache is full
+func (ss *suggestService) store(query models.SuggestQuery, suggestions []models.Suggestion) {
+	if ss.cacheTtl <= 0 {
+		return
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	now := time.Now()
+	if len(ss.cache) >= maxCachedSuggestions {
+		for key, entry := range ss.cache {
+			if now.After(entry.expiresAt) {
+				delete(ss.cache, key)
+			}
+		}
+		if len(ss.cache) >= maxCachedSuggestions {
+			ss.cache = make(map[models.SuggestQuery]cachedSuggestions)
+		}
+	}
+	ss.cache[query] = cachedSuggestions{
+		suggestions: suggestions,
+		expiresAt:   now.Add(ss.cacheTtl),
+	}
+}
+
+// RunRefresh periodically refreshes the suggested values and clears the cache
+func (ss *suggestService) RunRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ss.suggestRepo.RefreshSuggestions(ctx); err != nil {
+				ss.log.Error("failed to refresh suggestions", slog.String("error", err.Error()))
+				continue
+			}
+			ss.mu.Lock()
+			ss.cache = make(map[models.SuggestQuery]cachedSuggestions)
+			ss.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/auth"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+type memorySuggestRepo struct {
+	queries     []models.SuggestQuery
+	suggestions []models.Suggestion
+	err         error
+}
+
+func (m *memorySuggestRepo) GetSuggestions(_ context.Context, query models.SuggestQuery) ([]models.Suggestion, error) {
+	m.queries = append(m.queries, query)
+	return m.suggestions, m.err
+}
+
+func (m *memorySuggestRepo) RefreshSuggestions(context.Context) error {
+	return nil
+}
+
+func newTestSuggestService(repo suggestRepo, cacheTtl time.Duration) *suggestService {
+	accessCfg := config.AccessConfig{
+		DefaultRole: "guest",
+		Roles: map[string]config.RoleConfig{
+			"admin": {Owner: config.OwnerAccessFull},
+			"guest": {Owner: config.OwnerAccessMask},
+		},
+	}
+	return NewSuggestService(slog.New(slog.NewTextHandler(io.Discard, nil)), repo,
+		config.SuggestConfig{CacheTtl: cacheTtl}, accessCfg)
+}
+
+func TestSuggestCache(t *testing.T) {
+	repo := &memorySuggestRepo{suggestions: []models.Suggestion{{Value: "Lada", Count: 3}}}
+	ss := newTestSuggestService(repo, time.Minute)
+	ctx := context.Background()
+	for _, prefix := range []string{"La", "la", "LA"} {
+		got, err := ss.Suggest(ctx, models.SuggestQuery{Field: models.SuggestMark, Prefix: prefix, Limit: 10})
+		if err != nil {
+			t.Fatalf("Suggest() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, repo.suggestions) {
+			t.Errorf("Suggest() = %v, want %v", got, repo.suggestions)
+		}
+	}
+	if len(repo.queries) != 1 {
+		t.Fatalf("repository is queried %d times, want 1", len(repo.queries))
+	}
+	if repo.queries[0].Prefix != "la" {
+		t.Errorf("queried prefix = %s, want la", repo.queries[0].Prefix)
+	}
+	// other limit is other query
+	if _, err := ss.Suggest(ctx, models.SuggestQuery{Field: models.SuggestMark, Prefix: "la", Limit: 5}); err != nil {
+		t.Fatalf("Suggest() error = %v", err)
+	}
+	if len(repo.queries) != 2 {
+		t.Errorf("repository is queried %d times, want 2", len(repo.queries))
+	}
+}
+
+func TestSuggestCacheDisabledAndExpired(t *testing.T) {
+	query := models.SuggestQuery{Field: models.SuggestModel, Prefix: "ve", Limit: 10}
+	repo := &memorySuggestRepo{}
+	ss := newTestSuggestService(repo, 0)
+	for i := 0; i < 2; i++ {
+		if _, err := ss.Suggest(context.Background(), query); err != nil {
+			t.Fatalf("Suggest() error = %v", err)
+		}
+	}
+	if len(repo.queries) != 2 {
+		t.Errorf("repository without cache is queried %d times, want 2", len(repo.queries))
+	}
+
+	repo = &memorySuggestRepo{}
+	ss = newTestSuggestService(repo, time.Minute)
+	ss.cache[query] = cachedSuggestions{expiresAt: time.Now().Add(-time.Second)}
+	if _, err := ss.Suggest(context.Background(), query); err != nil {
+		t.Fatalf("Suggest() error = %v", err)
+	}
+	if len(repo.queries) != 1 {
+		t.Errorf("repository with expired entry is queried %d times, want 1", len(repo.queries))
+	}
+}
+
+func TestSuggestCacheErrorIsntStored(t *testing.T) {
+	repo := &memorySuggestRepo{err: errors.New("db is down")}
+	ss := newTestSuggestService(repo, time.Minute)
+	query := models.SuggestQuery{Field: models.SuggestMark, Limit: 10}
+	if _, err := ss.Suggest(context.Background(), query); !errors.Is(err, ErrGetSuggestions) {
+		t.Fatalf("Suggest() error = %v, want %v", err, ErrGetSuggestions)
+	}
+	repo.err = nil
+	if _, err := ss.Suggest(context.Background(), query); err != nil {
+		t.Fatalf("Suggest() error = %v", err)
+	}
+	if len(repo.queries) != 2 {
+		t.Errorf("repository is queried %d times, want 2", len(repo.queries))
+	}
+}
+
+func TestSuggestOwnerGate(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		principal *auth.Principal
+		wantErr   error
+	}{
+		{name: "owner name with full access", field: models.SuggestOwnerName, principal: &auth.Principal{Name: "a", Role: "admin"}},
+		{name: "owner surname with mask access", field: models.SuggestOwnerSurname, principal: &auth.Principal{Name: "g", Role: "guest"}, wantErr: ErrForbiddenSuggest},
+		{name: "owner name of anonymous", field: models.SuggestOwnerName, wantErr: ErrForbiddenSuggest},
+		{name: "owner name of unconfigured role", field: models.SuggestOwnerName, principal: &auth.Principal{Name: "x", Role: "unknown"}, wantErr: ErrForbiddenSuggest},
+		{name: "mark of anonymous", field: models.SuggestMark},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memorySuggestRepo{}
+			ss := newTestSuggestService(repo, 0)
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.ContextWithPrincipal(ctx, *tt.principal)
+			}
+			_, err := ss.Suggest(ctx, models.SuggestQuery{Field: tt.field, Limit: 10})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Suggest() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(repo.queries) != 0 {
+				t.Errorf("forbidden suggestions are queried")
+			}
+		})
+	}
+}
@@ -8,5 +8,11 @@ var (
 	ErrRollbackTx = errors.New("failed to rollback transaction")
 
 	ErrCarExist = errors.New("car with this register number already exist")
+	ErrVinExist = errors.New("car with this vin already exist")
 	ErrCarNotFound = errors.New("car with this id not found")
+
+	ErrCatalogExist    = errors.New("catalog entry with this name already exist")
+	ErrCatalogNotFound = errors.New("catalog entry not found")
+
+	ErrInvalidFilter = errors.New("filter value doesnt match the column type")
 )
\ No newline at end of file
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/jackc/pgx/v4"
+)
+
+// selectorCondition returns condition of the cars selected by ids or by filter
+func selectorCondition(selector models.CarSelector, argCount int) (string, []interface{}) {
+	if len(selector.Ids) != 0 {
+		return fmt.Sprintf("car_id = ANY($%d)", argCount+1), []interface{}{selector.Ids}
+	}
+	condition, usedData := filterCondition(selector.Filter, argCount)
+	if condition == "" {
+		return "true", nil
+	}
+	return "(" + condition + ")", usedData
+}
+
+// selectCarIds returns sorted ids of the selected cars, inside the transaction the rows are locked until its end
+func (pp *postgresProvider) selectCarIds(ctx context.Context, tx pgx.Tx, selector models.CarSelector) ([]int, error) {
+	condition, usedData := selectorCondition(selector, 0)
+	query := fmt.Sprintf(`SELECT car_id FROM "%s" WHERE %s ORDER BY car_id`, pp.cfg.CarTable, condition)
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if tx != nil {
+		rows, err = tx.Query(ctx, query+" FOR UPDATE", usedData...)
+	} else {
+		rows, err = pp.dbConn.Query(ctx, query, usedData...)
+	}
+	if err != nil {
+		return nil, mapFilterError(err)
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapFilterError(err)
+	}
+	return ids, nil
+}
+
+// GetCarIds returns sorted ids of the selected cars
+func (pp *postgresProvider) GetCarIds(ctx context.Context, selector models.CarSelector) (_ []int, err error) {
+	defer observe("GetCarIds", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "GetCarIds", "SELECT")
+	defer tracing.End(span, &err)
+	return pp.selectCarIds(ctx, nil, selector)
+}
+
+// UpdateCars applies new data to the selected cars in one transaction. The selected cars are locked
+// and their ids are passed to the check, error of the check rolls the transaction back
+func (pp *postgresProvider) UpdateCars(ctx context.Context, selector models.CarSelector, newData models.CarForPatch, check func([]int) error) (_ []int, err error) {
+	defer observe("UpdateCars", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "UpdateCars", "UPDATE")
+	defer tracing.End(span, &err)
+	return pp.inBatchTx(ctx, selector, check, func(tx pgx.Tx, ids []int) error {
+		set, usedData := patchSet(newData)
+		usedData = append(usedData, ids)
+		_, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE "%s" SET %s WHERE car_id = ANY($%d)`,
+			pp.cfg.CarTable, set, len(usedData)), usedData...)
+		return err
+	})
+}
+
+// DeleteCars deletes the selected cars in one transaction, the check is called as in UpdateCars
+func (pp *postgresProvider) DeleteCars(ctx context.Context, selector models.CarSelector, check func([]int) error) (_ []int, err error) {
+	defer observe("DeleteCars", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "DeleteCars", "DELETE")
+	defer tracing.End(span, &err)
+	return pp.inBatchTx(ctx, selector, check, func(tx pgx.Tx, ids []int) error {
+		_, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE car_id = ANY($1)`, pp.cfg.CarTable), ids)
+		return err
+	})
+}
+
+// inBatchTx locks the selected cars, checks them and applies the change to them
+func (pp *postgresProvider) inBatchTx(ctx context.Context, selector models.CarSelector, check func([]int) error, apply func(pgx.Tx, []int) error) ([]int, error) {
+	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, storage.ErrStartTx
+	}
+	rollback := func(err error) ([]int, error) {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, storage.ErrRollbackTx
+		}
+		return nil, err
+	}
+	ids, err := pp.selectCarIds(ctx, tx, selector)
+	if err != nil {
+		return rollback(err)
+	}
+	if check != nil {
+		if err := check(ids); err != nil {
+			return rollback(err)
+		}
+	}
+	if len(ids) != 0 {
+		if err := apply(tx, ids); err != nil {
+			return rollback(err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, storage.ErrCommitTx
+	}
+	return ids, nil
+}
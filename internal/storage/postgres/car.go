@@ -5,71 +5,127 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 )
 
-func (pp *postgresProvider) SaveCars(ctx context.Context, carList []models.Car) error {
-	tx, err := pp.dbConn.BeginTx(context.Background(), pgx.TxOptions{})
-	if err != nil {
-		return storage.ErrStartTx
+// unique constraint of the vin column
+const vinConstraint = "vin_uniq"
+
+// plate columns are selected as one json object, null if the register number isnt parsed
+const plateColumn = `CASE WHEN plate_type IS NULL THEN NULL ELSE json_build_object(
+	'type', plate_type, 'series', plate_series, 'number', plate_number, 'region', plate_region) END`
+
+// columns of the parsed register number
+var plateColumns = []string{"plate_type", "plate_series", "plate_number", "plate_region"}
+
+// plateValues returns values of the plate columns, nulls if the register number isnt parsed
+func plateValues(plate *models.Plate) []*string {
+	if plate == nil {
+		return make([]*string, len(plateColumns))
 	}
-	batch := pgx.Batch{}
-	for _, car := range carList {
-		batch.Queue(fmt.Sprintf(`
-			INSERT INTO "%s" (reg_num, mark, model, year, owner_name, owner_surname, owner_patronymic)
-			VALUES($1,$2,$3,$4,$5,$6,$7)
-			ON CONFLICT (reg_num) DO NOTHING;`,
-			pp.cfg.CarTable),
-			car.RegisterNumber, car.Mark, car.Model,
-			car.Year, car.Owner.Name, car.Owner.Surname,
-			car.Owner.Patronymic,
-		)
-	}
-	results := tx.SendBatch(ctx, &batch)
-	for range carList {
-		_, err = results.Exec()
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				continue
-			}
-			results.Close()
-			if err := tx.Rollback(ctx); err != nil {
-				return storage.ErrRollbackTx
-			}
-			return err
-		}
+	return []*string{&plate.Type, &plate.Series, &plate.Number, &plate.Region}
+}
+
+const carColumns = `car_id, reg_num, mark, model, year, owner_name, owner_surname, owner_patronymic, ` + plateColumn + `,
+	vin, source, fetched_at, created_at, updated_at, updated_by`
+
+func scanCar(row pgx.Row, car *models.Car) error {
+	car.Owner = &models.Owner{}
+	return row.Scan(
+		&car.Id,
+		&car.RegisterNumber,
+		&car.Mark,
+		&car.Model,
+		&car.Year,
+		&car.Owner.Name,
+		&car.Owner.Surname,
+		&car.Owner.Patronymic,
+		&car.Plate,
+		&car.Vin,
+		&car.Source,
+		&car.FetchedAt,
+		&car.CreatedAt,
+		&car.UpdatedAt,
+		&car.UpdatedBy)
+}
+
+// mapCarIdError returns ErrCarNotFound if car id cant be converted to the column type
+func mapCarIdError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+		return storage.ErrCarNotFound
 	}
-	results.Close()
-	if err := tx.Commit(ctx); err != nil {
-		return storage.ErrCommitTx
+	return err
+}
+
+// mapConflictError returns ErrVinExist or ErrCarExist if the unique constraint is violated
+func mapConflictError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
 	}
-	return nil
+	if pgErr.ConstraintName == vinConstraint {
+		return storage.ErrVinExist
+	}
+	return storage.ErrCarExist
 }
 
-func (pp *postgresProvider) GetCarById(ctx context.Context, carId string) (models.Car, error) {
+// mapFilterError returns ErrInvalidFilter if filter value cant be converted to the column type
+func mapFilterError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		// invalid_text_representation, invalid_datetime_format, datetime_field_overflow
+		case "22P02", "22007", "22008":
+			return fmt.Errorf("%w: %s", storage.ErrInvalidFilter, pgErr.Message)
+		}
+	}
+	return err
+}
+
+func (pp *postgresProvider) GetCarById(ctx context.Context, carId string) (_ models.Car, err error) {
+	defer observe("GetCarById", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "GetCarById", "SELECT")
+	defer tracing.End(span, &err)
 	row := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
-		SELECT car_id, reg_num, mark, model, year, owner_name, owner_surname, owner_patronymic
+		SELECT %s
 		FROM "%s"
 		WHERE car_id = $1;`,
-	pp.cfg.CarTable),
+	carColumns, pp.cfg.CarTable),
 	carId)
 	var (
 		car models.Car
 	)
-	err := row.Scan(
-		&car.Id,
-		&car.RegisterNumber,
-		&car.Mark,
-		&car.Model,
-		&car.Year,
-		&car.Owner.Name,
-		&car.Owner.Surname,
-		&car.Owner.Patronymic)
+	err = scanCar(row, &car)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Car{}, storage.ErrCarNotFound
+		}
+		return models.Car{}, mapCarIdError(err)
+	}
+	return car, nil
+}
+
+func (pp *postgresProvider) GetCarByVin(ctx context.Context, vin string) (_ models.Car, err error) {
+	defer observe("GetCarByVin", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "GetCarByVin", "SELECT")
+	defer tracing.End(span, &err)
+	row := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM "%s"
+		WHERE vin = $1;`,
+	carColumns, pp.cfg.CarTable),
+	vin)
+	var (
+		car models.Car
+	)
+	err = scanCar(row, &car)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return models.Car{}, storage.ErrCarNotFound
@@ -79,72 +135,165 @@ func (pp *postgresProvider) GetCarById(ctx context.Context, carId string) (model
 	return car, nil
 }
 
-func (pp *postgresProvider) GetCarsWithFilterAndPagination(ctx context.Context, pgOption models.PaginationOption, filter models.Filter) ([]models.Car, error) {
+// buildCarsQuery returns select query of the cars with filter, sorting and pagination,
+// its arguments and scan targets of the selected fields (nil if all fields are selected)
+func (pp *postgresProvider) buildCarsQuery(pgOption models.PaginationOption, filter models.Filter) (string, []interface{}, func(car *models.Car) []interface{}) {
+	// field names are checked by AddFields
+	columns, targets := selectCarFields(pgOption.Fields)
 	var preparedQuery strings.Builder
 	preparedQuery.WriteString(
 		fmt.Sprintf(
-			`SELECT car_id, reg_num, mark, model, year, owner_name, owner_surname, owner_patronymic FROM "%s" `,
-		pp.cfg.CarTable))
+			`SELECT %s FROM "%s" `,
+		columns, pp.cfg.CarTable))
+	where, usedData := filterCondition(filter, 0)
+	if where != "" {
+		preparedQuery.WriteString("WHERE " + where + " ")
+	}
+	fieldCount := len(usedData)
+	if len(pgOption.Sort) != 0 {
+		orderBy := make([]string, 0, len(pgOption.Sort)+1)
+		for _, sortField := range pgOption.Sort {
+			direction := "ASC"
+			if sortField.Desc {
+				direction = "DESC"
+			}
+			// column names are checked by AddSort
+			orderBy = append(orderBy, fmt.Sprintf("%s %s", sortField.Name, direction))
+		}
+		// keeps pages stable when sorted values are equal
+		orderBy = append(orderBy, "car_id ASC")
+		preparedQuery.WriteString(fmt.Sprintf("ORDER BY %s ", strings.Join(orderBy, ", ")))
+	}
+	if pgOption.Limit != 0 {
+		preparedQuery.WriteString(fmt.Sprintf("LIMIT $%d OFFSET $%d", fieldCount+1, fieldCount+2))
+		usedData = append(usedData, pgOption.Limit, pgOption.Offset)
+	}
+	return preparedQuery.String(), usedData, targets
+}
+
+// filterCondition returns condition of the filter with arguments numbered after argCount,
+// empty condition if the filter doesnt contain fields
+func filterCondition(filter models.Filter, argCount int) (string, []interface{}) {
 	var (
-		fieldCount int
+		condition strings.Builder
 		usedData []interface{}
 	)
-	filterCount := 0
-	filters := filter.Fields
-	if len(filters) != 0 {
-		preparedQuery.WriteString("WHERE ")
-	} 
-	for _, field := range filters {
-		if filterCount != 0 {
-			preparedQuery.WriteString(field.UnionCondition + " ")
+	for i, field := range filter.Fields {
+		if i != 0 {
+			condition.WriteString(" " + field.UnionCondition + " ")
 		}
 		// SQL INJECTION, cant pass columns name via argument
-		preparedQuery.WriteString(fmt.Sprintf("%s %s $%d ", field.Name, field.Operator, fieldCount+1))
+		condition.WriteString(fmt.Sprintf("%s %s $%d", field.Name, field.Operator, argCount+i+1))
 		usedData = append(usedData, field.Value)
-		fieldCount+=1
-		filterCount++
 	}
-	if pgOption.Limit != 0 {
-		preparedQuery.WriteString(fmt.Sprintf("LIMIT $%d OFFSET $%d", fieldCount+1, fieldCount+2))
-		usedData = append(usedData, pgOption.Limit, pgOption.Offset)
+	return condition.String(), usedData
+}
+
+// scanCarFields scans all fields of the car or only the selected ones
+func scanCarFields(rows pgx.Rows, car *models.Car, targets func(car *models.Car) []interface{}) error {
+	if targets != nil {
+		return rows.Scan(targets(car)...)
 	}
-	rows, err := pp.dbConn.Query(ctx, preparedQuery.String(), usedData...)
+	return scanCar(rows, car)
+}
+
+func (pp *postgresProvider) GetCarsWithFilterAndPagination(ctx context.Context, pgOption models.PaginationOption, filter models.Filter) (_ []models.Car, err error) {
+	defer observe("GetCarsWithFilterAndPagination", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "GetCarsWithFilterAndPagination", "SELECT")
+	defer tracing.End(span, &err)
+	query, usedData, targets := pp.buildCarsQuery(pgOption, filter)
+	rows, err := pp.dbConn.Query(ctx, query, usedData...)
 	if err != nil {
-		return nil, err
+		return nil, mapFilterError(err)
 	}
 	var outProducts []models.Car
 	for rows.Next() {
 		var car models.Car
-		err := rows.Scan(
-			&car.Id,
-			&car.RegisterNumber,
-			&car.Mark,
-			&car.Model,
-			&car.Year,
-			&car.Owner.Name,
-			&car.Owner.Surname,
-			&car.Owner.Patronymic)
+		err = scanCarFields(rows, &car, targets)
 		if err != nil {
+			rows.Close()
 			return nil, err
 		}
 		outProducts = append(outProducts, car)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, mapFilterError(err)
+	}
 	return outProducts, nil
 }
 
-func (pp *postgresProvider) UpdateCarById(ctx context.Context, carId string, newData models.CarForPatch) error {
+// StreamCarsWithFilter reads cars from the cursor one by one and passes them to the callback,
+// so the memory doesnt depend on the number of the cars. Error of the callback stops reading
+func (pp *postgresProvider) StreamCarsWithFilter(ctx context.Context, pgOption models.PaginationOption, filter models.Filter, callback func(models.Car) error) (err error) {
+	defer observe("StreamCarsWithFilter", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "StreamCarsWithFilter", "SELECT")
+	defer tracing.End(span, &err)
+	query, usedData, targets := pp.buildCarsQuery(pgOption, filter)
+	rows, err := pp.dbConn.Query(ctx, query, usedData...)
+	if err != nil {
+		return mapFilterError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var car models.Car
+		if err = scanCarFields(rows, &car, targets); err != nil {
+			return err
+		}
+		if err = callback(car); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return mapFilterError(err)
+	}
+	return nil
+}
+
+func (pp *postgresProvider) UpdateCarById(ctx context.Context, carId string, newData models.CarForPatch) (err error) {
+	defer observe("UpdateCarById", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "UpdateCarById", "UPDATE")
+	defer tracing.End(span, &err)
 	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return storage.ErrStartTx
 	}
+	set, usedData := patchSet(newData)
+	usedData = append(usedData, carId)
+	tag, err := tx.Exec(ctx, fmt.Sprintf("UPDATE \"%s\" SET %s WHERE \"car_id\" = $%d", pp.cfg.CarTable, set, len(usedData)), usedData...)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return storage.ErrRollbackTx
+		}
+		return mapCarIdError(mapConflictError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		if err := tx.Rollback(ctx); err != nil {
+			return storage.ErrRollbackTx
+		}
+		return storage.ErrCarNotFound
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// patchSet returns assignments of the not nil fields of the new car data and provenance fields
+func patchSet(newData models.CarForPatch) (string, []interface{}) {
 	var preparedQuery strings.Builder
-	preparedQuery.WriteString(fmt.Sprintf("UPDATE \"%s\" SET ", pp.cfg.CarTable)) 
 	fieldsCount := 0
 	var usedData []interface{}
 	if newData.RegisterNumber != nil {
 		fieldsCount++
 		preparedQuery.WriteString(fmt.Sprintf("\"reg_num\" = $%d, ", fieldsCount))
 		usedData = append(usedData, *newData.RegisterNumber)
+		// plate is changed with the register number
+		plate := plateValues(newData.Plate)
+		for i, column := range plateColumns {
+			fieldsCount++
+			preparedQuery.WriteString(fmt.Sprintf("\"%s\" = $%d, ", column, fieldsCount))
+			usedData = append(usedData, plate[i])
+		}
 	}
 	if newData.Mark != nil {
 		fieldsCount++
@@ -182,25 +331,68 @@ func (pp *postgresProvider) UpdateCarById(ctx context.Context, carId string, new
 			preparedQuery.WriteString(fmt.Sprintf("\"owner_patronymic\" = $%d, ", fieldsCount))
 			
 			usedData = append(usedData, *newData.Owner.Patronymic)
+		} else if newData.Owner.ClearPatronymic {
+			preparedQuery.WriteString("\"owner_patronymic\" = NULL, ")
 		}
 	}
-	query :=  preparedQuery.String()
-	if fieldsCount != 0 {
-		query = query[:len(query)-2]
+	if newData.Vin != nil {
+		fieldsCount++
+		preparedQuery.WriteString(fmt.Sprintf("\"vin\" = $%d, ", fieldsCount))
+		usedData = append(usedData, *newData.Vin)
+	} else if newData.ClearVin {
+		preparedQuery.WriteString("\"vin\" = NULL, ")
+	}
+	source := newData.Source
+	if source == "" {
+		source = models.SourceManual
 	}
-	usedData = append(usedData, carId)
-	_, err = tx.Exec(ctx, fmt.Sprintf("%s WHERE \"car_id\" = $%d", query, fieldsCount+1), usedData...)
+	fieldsCount++
+	preparedQuery.WriteString(fmt.Sprintf("\"source\" = $%d, ", fieldsCount))
+	usedData = append(usedData, source)
+	fieldsCount++
+	preparedQuery.WriteString(fmt.Sprintf("\"updated_by\" = $%d, ", fieldsCount))
+	usedData = append(usedData, newData.UpdatedBy)
+	preparedQuery.WriteString("\"updated_at\" = now()")
+	return preparedQuery.String(), usedData
+}
+
+func (pp *postgresProvider) ReplaceCarById(ctx context.Context, carId string, newCar models.Car) (err error) {
+	defer observe("ReplaceCarById", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "ReplaceCarById", "UPDATE")
+	defer tracing.End(span, &err)
+	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	source := newCar.Source
+	if source == "" {
+		source = models.SourceManual
+	}
+	plate := plateValues(newCar.Plate)
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE "%s" SET
+			"reg_num" = $1, "mark" = $2, "model" = $3, "year" = $4,
+			"owner_name" = $5, "owner_surname" = $6, "owner_patronymic" = $7,
+			"plate_type" = $8, "plate_series" = $9, "plate_number" = $10, "plate_region" = $11,
+			"vin" = $12, "source" = $13, "updated_by" = $14, "updated_at" = now()
+		WHERE "car_id" = $15`,
+		pp.cfg.CarTable),
+		newCar.RegisterNumber, newCar.Mark, newCar.Model, newCar.Year,
+		newCar.Owner.Name, newCar.Owner.Surname, newCar.Owner.Patronymic,
+		plate[0], plate[1], plate[2], plate[3],
+		newCar.Vin, source, newCar.UpdatedBy, carId,
+	)
 	if err != nil {
 		if err := tx.Rollback(ctx); err != nil {
 			return storage.ErrRollbackTx
 		}
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				return storage.ErrCarExist
-			}
+		return mapCarIdError(mapConflictError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		if err := tx.Rollback(ctx); err != nil {
+			return storage.ErrRollbackTx
 		}
-		return err
+		return storage.ErrCarNotFound
 	}
 	if err := tx.Commit(ctx); err != nil {
 		return storage.ErrCommitTx
@@ -208,17 +400,26 @@ func (pp *postgresProvider) UpdateCarById(ctx context.Context, carId string, new
 	return nil
 }
 
-func (pp *postgresProvider) DeleteCarById(ctx context.Context, carId string) error {
+func (pp *postgresProvider) DeleteCarById(ctx context.Context, carId string) (err error) {
+	defer observe("DeleteCarById", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "DeleteCarById", "DELETE")
+	defer tracing.End(span, &err)
 	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return storage.ErrStartTx
 	}
-	_, err = tx.Exec(ctx, fmt.Sprintf("DELETE FROM \"%s\" WHERE \"car_id\" = $1", pp.cfg.CarTable), carId)
+	tag, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM \"%s\" WHERE \"car_id\" = $1", pp.cfg.CarTable), carId)
 	if err != nil {
 		if err := tx.Rollback(ctx); err != nil {
 			return storage.ErrRollbackTx
 		}
-		return err
+		return mapCarIdError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		if err := tx.Rollback(ctx); err != nil {
+			return storage.ErrRollbackTx
+		}
+		return storage.ErrCarNotFound
 	}
 	if err := tx.Commit(ctx); err != nil {
 		return storage.ErrCommitTx
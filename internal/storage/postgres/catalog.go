@@ -0,0 +1,262 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// catalogQuerier reads the catalog from the pool or from the transaction
+type catalogQuerier interface {
+	Query(context.Context, string, ...interface{}) (pgx.Rows, error)
+}
+
+// mapCatalogError returns ErrCatalogExist if the name is already used and ErrCatalogNotFound
+// if the mark of the model doesnt exist
+func mapCatalogError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		// unique_violation
+		case "23505":
+			return storage.ErrCatalogExist
+		// foreign_key_violation
+		case "23503":
+			return storage.ErrCatalogNotFound
+		}
+	}
+	return err
+}
+
+// GetCatalog returns all marks with their models sorted by names
+func (pp *postgresProvider) GetCatalog(ctx context.Context) (_ []models.CatalogMark, err error) {
+	defer observe("GetCatalog", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.MarkTable, "GetCatalog", "SELECT")
+	defer tracing.End(span, &err)
+	return pp.getCatalog(ctx, pp.dbConn)
+}
+
+func (pp *postgresProvider) getCatalog(ctx context.Context, q catalogQuerier) ([]models.CatalogMark, error) {
+	rows, err := q.Query(ctx, fmt.Sprintf(`
+		SELECT mark_id, name, aliases
+		FROM "%s"
+		ORDER BY name;`,
+	pp.cfg.MarkTable))
+	if err != nil {
+		return nil, err
+	}
+	marks := []models.CatalogMark{}
+	positions := make(map[int]int)
+	for rows.Next() {
+		mark := models.CatalogMark{Models: []models.CatalogModel{}}
+		if err = rows.Scan(&mark.Id, &mark.Name, &mark.Aliases); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		positions[mark.Id] = len(marks)
+		marks = append(marks, mark)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = q.Query(ctx, fmt.Sprintf(`
+		SELECT model_id, mark_id, name, aliases
+		FROM "%s"
+		ORDER BY name;`,
+	pp.cfg.ModelTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var model models.CatalogModel
+		if err = rows.Scan(&model.Id, &model.MarkId, &model.Name, &model.Aliases); err != nil {
+			return nil, err
+		}
+		// the mark can be added after the first query
+		if pos, ok := positions[model.MarkId]; ok {
+			marks[pos].Models = append(marks[pos].Models, model)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return marks, nil
+}
+
+// changeCatalog runs the check and the change in one transaction, the check gets the catalog seen by the transaction. The catalog tables are locked against
+// the other changes until the commit, so the names cant be taken between the check and the change.
+// Readers arent blocked
+func (pp *postgresProvider) changeCatalog(ctx context.Context, check func([]models.CatalogMark) error, change func(pgx.Tx) error) error {
+	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	// rollback after the commit does nothing
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`LOCK TABLE "%s", "%s" IN SHARE ROW EXCLUSIVE MODE`, pp.cfg.MarkTable, pp.cfg.ModelTable))
+	if err != nil {
+		return err
+	}
+	marks, err := pp.getCatalog(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err = check(marks); err != nil {
+		return err
+	}
+	if err = change(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// SaveCatalogMark saves the mark if the check of the current catalog passes
+func (pp *postgresProvider) SaveCatalogMark(ctx context.Context, mark models.CatalogMark, check func([]models.CatalogMark) error) (_ models.CatalogMark, err error) {
+	defer observe("SaveCatalogMark", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.MarkTable, "SaveCatalogMark", "INSERT")
+	defer tracing.End(span, &err)
+	err = pp.changeCatalog(ctx, check, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, fmt.Sprintf(`
+			INSERT INTO "%s" (name, aliases)
+			VALUES ($1, $2)
+			RETURNING mark_id;`,
+		pp.cfg.MarkTable),
+		mark.Name, mark.Aliases).Scan(&mark.Id)
+	})
+	if err != nil {
+		return models.CatalogMark{}, mapCatalogError(err)
+	}
+	return mark, nil
+}
+
+// UpdateCatalogMark changes name and aliases of the mark if the check of the current catalog passes
+func (pp *postgresProvider) UpdateCatalogMark(ctx context.Context, mark models.CatalogMark, check func([]models.CatalogMark) error) (err error) {
+	defer observe("UpdateCatalogMark", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.MarkTable, "UpdateCatalogMark", "UPDATE")
+	defer tracing.End(span, &err)
+	err = pp.changeCatalog(ctx, check, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			UPDATE "%s" SET name = $1, aliases = $2
+			WHERE mark_id = $3;`,
+		pp.cfg.MarkTable),
+		mark.Name, mark.Aliases, mark.Id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrCatalogNotFound
+		}
+		return nil
+	})
+	return mapCatalogError(err)
+}
+
+// DeleteCatalogMark deletes the mark with its models
+func (pp *postgresProvider) DeleteCatalogMark(ctx context.Context, markId int) (err error) {
+	defer observe("DeleteCatalogMark", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.MarkTable, "DeleteCatalogMark", "DELETE")
+	defer tracing.End(span, &err)
+	tag, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE mark_id = $1`, pp.cfg.MarkTable), markId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrCatalogNotFound
+	}
+	return nil
+}
+
+// SaveCatalogModel saves the model if the check of the current catalog passes
+func (pp *postgresProvider) SaveCatalogModel(ctx context.Context, model models.CatalogModel, check func([]models.CatalogMark) error) (_ models.CatalogModel, err error) {
+	defer observe("SaveCatalogModel", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.ModelTable, "SaveCatalogModel", "INSERT")
+	defer tracing.End(span, &err)
+	err = pp.changeCatalog(ctx, check, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, fmt.Sprintf(`
+			INSERT INTO "%s" (mark_id, name, aliases)
+			VALUES ($1, $2, $3)
+			RETURNING model_id;`,
+		pp.cfg.ModelTable),
+		model.MarkId, model.Name, model.Aliases).Scan(&model.Id)
+	})
+	if err != nil {
+		return models.CatalogModel{}, mapCatalogError(err)
+	}
+	return model, nil
+}
+
+// UpdateCatalogModel changes name and aliases of the model like UpdateCatalogMark, the mark of the model isnt changed
+func (pp *postgresProvider) UpdateCatalogModel(ctx context.Context, model models.CatalogModel, check func([]models.CatalogMark) error) (err error) {
+	defer observe("UpdateCatalogModel", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.ModelTable, "UpdateCatalogModel", "UPDATE")
+	defer tracing.End(span, &err)
+	err = pp.changeCatalog(ctx, check, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			UPDATE "%s" SET name = $1, aliases = $2
+			WHERE model_id = $3;`,
+		pp.cfg.ModelTable),
+		model.Name, model.Aliases, model.Id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrCatalogNotFound
+		}
+		return nil
+	})
+	return mapCatalogError(err)
+}
+
+func (pp *postgresProvider) DeleteCatalogModel(ctx context.Context, modelId int) (err error) {
+	defer observe("DeleteCatalogModel", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.ModelTable, "DeleteCatalogModel", "DELETE")
+	defer tracing.End(span, &err)
+	tag, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE model_id = $1`, pp.cfg.ModelTable), modelId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrCatalogNotFound
+	}
+	return nil
+}
+
+// GetMarkModelCounts returns the number of the cars by their marks and models
+func (pp *postgresProvider) GetMarkModelCounts(ctx context.Context) (_ []models.MarkModelCount, err error) {
+	defer observe("GetMarkModelCounts", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "GetMarkModelCounts", "SELECT")
+	defer tracing.End(span, &err)
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+		SELECT mark, model, count(*)
+		FROM "%s"
+		GROUP BY mark, model
+		ORDER BY mark, model;`,
+	pp.cfg.CarTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var counts []models.MarkModelCount
+	for rows.Next() {
+		var count models.MarkModelCount
+		if err = rows.Scan(&count.Mark, &count.Model, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
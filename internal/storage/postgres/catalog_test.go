@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/jackc/pgconn"
+)
+
+func TestMapCatalogError(t *testing.T) {
+	other := errors.New("connection refused")
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, want: storage.ErrCatalogExist},
+		{name: "wrapped unique violation", err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), want: storage.ErrCatalogExist},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: "23503"}, want: storage.ErrCatalogNotFound},
+		{name: "other postgres error", err: &pgconn.PgError{Code: "42P01"}},
+		{name: "other error", err: other, want: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapCatalogError(tt.err)
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+			if got != want {
+				t.Errorf("mapCatalogError() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+type carField struct {
+	columns string
+	targets func(car *models.Car) []interface{}
+}
+
+// carFields maps json names of the car fields to their columns and scan targets
+var carFields = map[string]carField{
+	"carId":  {"car_id", func(car *models.Car) []interface{} { return []interface{}{&car.Id} }},
+	"regNum": {"reg_num", func(car *models.Car) []interface{} { return []interface{}{&car.RegisterNumber} }},
+	"mark":   {"mark", func(car *models.Car) []interface{} { return []interface{}{&car.Mark} }},
+	"model":  {"model", func(car *models.Car) []interface{} { return []interface{}{&car.Model} }},
+	"year":   {"year", func(car *models.Car) []interface{} { return []interface{}{&car.Year} }},
+	"owner": {"owner_name, owner_surname, owner_patronymic", func(car *models.Car) []interface{} {
+		car.Owner = &models.Owner{}
+		return []interface{}{&car.Owner.Name, &car.Owner.Surname, &car.Owner.Patronymic}
+	}},
+	"plate":     {plateColumn, func(car *models.Car) []interface{} { return []interface{}{&car.Plate} }},
+	"vin":       {"vin", func(car *models.Car) []interface{} { return []interface{}{&car.Vin} }},
+	// vin info is decoded from the vin by the service
+	"vinInfo":   {"vin", func(car *models.Car) []interface{} { return []interface{}{&car.Vin} }},
+	"source":    {"source", func(car *models.Car) []interface{} { return []interface{}{&car.Source} }},
+	"fetchedAt": {"fetched_at", func(car *models.Car) []interface{} { return []interface{}{&car.FetchedAt} }},
+	"createdAt": {"created_at", func(car *models.Car) []interface{} { return []interface{}{&car.CreatedAt} }},
+	"updatedAt": {"updated_at", func(car *models.Car) []interface{} { return []interface{}{&car.UpdatedAt} }},
+	"updatedBy": {"updated_by", func(car *models.Car) []interface{} { return []interface{}{&car.UpdatedBy} }},
+}
+
+// AddFields parse comma separated json names of the car fields which must be selected
+func AddFields(pgOption *models.PaginationOption, value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := carFields[name]; !ok {
+			return fmt.Errorf("unknown field %s", name)
+		}
+		found := false
+		for _, added := range pgOption.Fields {
+			if added == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pgOption.Fields = append(pgOption.Fields, name)
+		}
+	}
+	return nil
+}
+
+// selectCarFields returns columns of the fields and function which returns scan targets,
+// all columns are selected if fields are empty
+func selectCarFields(fields []string) (string, func(car *models.Car) []interface{}) {
+	if len(fields) == 0 {
+		return carColumns, nil
+	}
+	columns := make([]string, 0, len(fields))
+	for _, name := range fields {
+		columns = append(columns, carFields[name].columns)
+	}
+	return strings.Join(columns, ", "), func(car *models.Car) []interface{} {
+		targets := make([]interface{}, 0, len(fields))
+		for _, name := range fields {
+			targets = append(targets, carFields[name].targets(car)...)
+		}
+		return targets
+	}
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestAddFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "one value", values: []string{"regNum, mark"}, want: []string{"regNum", "mark"}},
+		{name: "several values", values: []string{"regNum", "owner,vin"}, want: []string{"regNum", "owner", "vin"}},
+		{name: "repeated fields", values: []string{"mark,regNum,mark", "regNum"}, want: []string{"mark", "regNum"}},
+		{name: "empty names", values: []string{",mark,,"}, want: []string{"mark"}},
+		{name: "unknown field", values: []string{"regNum,color"}, wantErr: true},
+		{name: "column name", values: []string{"reg_num"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pgOption models.PaginationOption
+			var err error
+			for _, value := range tt.values {
+				if err = AddFields(&pgOption, value); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddFields() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(pgOption.Fields, tt.want) {
+				t.Errorf("fields = %v, want %v", pgOption.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectCarFields(t *testing.T) {
+	if columns, targets := selectCarFields(nil); columns != carColumns || targets != nil {
+		t.Errorf("all columns must be selected without fields, got %s", columns)
+	}
+	columns, targets := selectCarFields([]string{"regNum", "owner"})
+	if want := "reg_num, owner_name, owner_surname, owner_patronymic"; columns != want {
+		t.Errorf("columns = %s, want %s", columns, want)
+	}
+	var car models.Car
+	if got := len(targets(&car)); got != 4 {
+		t.Errorf("number of targets = %d, want 4", got)
+	}
+	if car.Owner == nil {
+		t.Errorf("owner isnt created for the scan")
+	}
+}
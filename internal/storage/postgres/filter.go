@@ -8,49 +8,99 @@ import (
 )
 
 func AddFilter(filter *models.Filter, name, value string) error {
-	operator := "="
 	field := models.Field{
 		UnionCondition: "AND",
 		Name: name,
-		Operator: operator,
+		Operator: "=",
 		Value: value,
 	}
 	splited := strings.Split(value, ":")
-	if len(splited) == 1 {
+	// the segments are the union word and the operator only if they are known keywords,
+	// so values like timestamps can contain the separator
+	pos := 0
+	if pos < len(splited)-1 {
+		if union, ok := filterUnions[strings.ToLower(splited[pos])]; ok {
+			field.UnionCondition = union
+			pos++
+		}
+	}
+	if pos < len(splited)-1 {
+		if operator, ok := filterOperators[strings.ToLower(splited[pos])]; ok {
+			field.Operator = operator
+			pos++
+		}
+	}
+	if pos == 0 {
 		filter.Fields = append(filter.Fields, field)
 		return nil
 	}
+	field.Value = strings.Join(splited[pos:], ":")
 	// how to distinguish a missing value from an empty one?
-	if len(splited) == 2 && splited[1] == "" {
+	if field.Value == "" {
 		return fmt.Errorf("empty value of filters field")
 	}
-	operatorPos := 0
-	switch strings.ToLower(splited[0]) {
-	case "and":
-		operatorPos++
-	case "or":
-		field.UnionCondition = "OR"
-		operatorPos++
-	default:
-	}
-	switch strings.ToLower(splited[operatorPos]) {
-	case "eq":
-	case "neq":
-		field.Operator = "<>"
-	case "gt":
-		field.Operator = ">"
-	case "get":
-		field.Operator = ">="
-	case "lt":
-		field.Operator = "<"
-	case "let":
-		field.Operator = "<="
-	case "like":
-		field.Operator = "LIKE"
-	default:
-		return fmt.Errorf("not valid filter operator")			
-	}
-	field.Value = strings.Join(splited[operatorPos+1:], "")
 	filter.Fields = append(filter.Fields, field)
 	return nil
+}
+
+// union words of the filter value
+var filterUnions = map[string]string{
+	"and": "AND",
+	"or":  "OR",
+}
+
+// operators of the filter value
+var filterOperators = map[string]string{
+	"eq":   "=",
+	"neq":  "<>",
+	"gt":   ">",
+	"get":  ">=",
+	"lt":   "<",
+	"let":  "<=",
+	"like": "LIKE",
+}
+
+// columns that can be used for sorting
+var sortableColumns = map[string]struct{}{
+	"car_id":           {},
+	"reg_num":          {},
+	"mark":             {},
+	"model":            {},
+	"year":             {},
+	"owner_name":       {},
+	"owner_surname":    {},
+	"owner_patronymic": {},
+	"plate_type":       {},
+	"plate_region":     {},
+	"vin":              {},
+	"source":           {},
+	"fetched_at":       {},
+	"created_at":       {},
+	"updated_at":       {},
+	"updated_by":       {},
+}
+
+// AddSort parse value in format col_name:direction, where direction is asc or desc (asc by default)
+func AddSort(pgOption *models.PaginationOption, value string) error {
+	splited := strings.Split(value, ":")
+	if len(splited) > 2 {
+		return fmt.Errorf("not valid sort format")
+	}
+	sortField := models.SortField{
+		Name: strings.ToLower(splited[0]),
+	}
+	if _, ok := sortableColumns[sortField.Name]; !ok {
+		return fmt.Errorf("not sortable field")
+	}
+	if len(splited) == 2 {
+		switch strings.ToLower(splited[1]) {
+		case "asc":
+		case "desc":
+			sortField.Desc = true
+		default:
+			return fmt.Errorf("not valid sort direction")
+		}
+	}
+	pgOption.Sort = append(pgOption.Sort, sortField)
+	return nil
 }
\ No newline at end of file
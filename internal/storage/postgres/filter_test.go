@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestAddFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    models.Field
+		wantErr bool
+	}{
+		{name: "value", value: "Lada", want: models.Field{UnionCondition: "AND", Operator: "=", Value: "Lada"}},
+		{name: "operator", value: "gt:2001", want: models.Field{UnionCondition: "AND", Operator: ">", Value: "2001"}},
+		{name: "union and operator", value: "OR:Like:Lada%", want: models.Field{UnionCondition: "OR", Operator: "LIKE", Value: "Lada%"}},
+		{name: "union", value: "or:Lada", want: models.Field{UnionCondition: "OR", Operator: "=", Value: "Lada"}},
+		{
+			name:  "timestamp",
+			value: "2024-01-01T10:00:00Z",
+			want:  models.Field{UnionCondition: "AND", Operator: "=", Value: "2024-01-01T10:00:00Z"},
+		},
+		{
+			name:  "timestamp with operator",
+			value: "and:get:2024-01-01T10:00:00Z",
+			want:  models.Field{UnionCondition: "AND", Operator: ">=", Value: "2024-01-01T10:00:00Z"},
+		},
+		{name: "unknown operator is value", value: "ab:cd", want: models.Field{UnionCondition: "AND", Operator: "=", Value: "ab:cd"}},
+		{name: "keyword without separator is value", value: "like", want: models.Field{UnionCondition: "AND", Operator: "=", Value: "like"}},
+		{name: "empty value", value: "gt:", wantErr: true},
+		{name: "empty value with union", value: "or:eq:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter models.Filter
+			err := AddFilter(&filter, "updated_at", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddFilter() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(filter.Fields) != 0 {
+					t.Errorf("filter with the error is added: %+v", filter.Fields)
+				}
+				return
+			}
+			tt.want.Name = "updated_at"
+			if !reflect.DeepEqual(filter.Fields, []models.Field{tt.want}) {
+				t.Errorf("AddFilter() fields = %+v, want %+v", filter.Fields, []models.Field{tt.want})
+			}
+		})
+	}
+}
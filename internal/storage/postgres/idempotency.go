@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/jackc/pgx/v4"
+)
+
+// number of the attempts to reserve the key which is deleted by the other request during the reservation
+const reserveAttempts = 3
+
+// ReserveIdempotencyKey saves the key if it doesnt exist or is created before expiredBefore. The key without response
+// is taken over after staleBefore, its request is lost with the crashed instance. If the key is already reserved,
+// its record is returned with false
+func (pp *postgresProvider) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord, expiredBefore, staleBefore time.Time) (_ models.IdempotencyRecord, reserved bool, err error) {
+	defer observe("ReserveIdempotencyKey", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.IdempotencyTable, "ReserveIdempotencyKey", "INSERT")
+	defer tracing.End(span, &err)
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		err = pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+			INSERT INTO "%[1]s" AS i (scope, idempotency_key, request_hash)
+			VALUES($1,$2,$3)
+			ON CONFLICT (scope, idempotency_key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash,
+				status = 0,
+				content_type = NULL,
+				body = NULL,
+				created_at = now()
+			WHERE i.created_at < $4 OR (i.status = 0 AND i.created_at < $5)
+			RETURNING created_at;`,
+		pp.cfg.IdempotencyTable),
+		record.Scope, record.Key, record.RequestHash, expiredBefore, staleBefore).Scan(&record.CreatedAt)
+		if err == nil {
+			return record, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return models.IdempotencyRecord{}, false, err
+		}
+		existing := models.IdempotencyRecord{
+			Scope: record.Scope,
+			Key:   record.Key,
+		}
+		var contentType *string
+		err = pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+			SELECT request_hash, status, content_type, body, created_at
+			FROM "%s"
+			WHERE scope = $1 AND idempotency_key = $2;`,
+		pp.cfg.IdempotencyTable),
+		record.Scope, record.Key).Scan(&existing.RequestHash, &existing.Status, &contentType, &existing.Body, &existing.CreatedAt)
+		if err != nil {
+			// the key was released after the failed request, so it is reserved again
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return models.IdempotencyRecord{}, false, err
+		}
+		if contentType != nil {
+			existing.ContentType = *contentType
+		}
+		return existing, false, nil
+	}
+	return models.IdempotencyRecord{}, false, fmt.Errorf("key is released during reservation %d times", reserveAttempts)
+}
+
+// SaveIdempotencyResponse saves response of the request with the reserved key,
+// nothing is saved if the reservation is taken over by the other request
+func (pp *postgresProvider) SaveIdempotencyResponse(ctx context.Context, record models.IdempotencyRecord) (err error) {
+	defer observe("SaveIdempotencyResponse", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.IdempotencyTable, "SaveIdempotencyResponse", "UPDATE")
+	defer tracing.End(span, &err)
+	_, err = pp.dbConn.Exec(ctx, fmt.Sprintf(`
+		UPDATE "%s"
+		SET status = $3, content_type = $4, body = $5
+		WHERE scope = $1 AND idempotency_key = $2 AND created_at = $6;`,
+	pp.cfg.IdempotencyTable),
+	record.Scope, record.Key, record.Status, record.ContentType, record.Body, record.CreatedAt)
+	return err
+}
+
+// DeleteIdempotencyKey releases the reservation of the key, so the request can be repeated
+func (pp *postgresProvider) DeleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (err error) {
+	defer observe("DeleteIdempotencyKey", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.IdempotencyTable, "DeleteIdempotencyKey", "DELETE")
+	defer tracing.End(span, &err)
+	_, err = pp.dbConn.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM "%s"
+		WHERE scope = $1 AND idempotency_key = $2 AND created_at = $3;`,
+	pp.cfg.IdempotencyTable),
+	record.Scope, record.Key, record.CreatedAt)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys deletes keys which are created before expiredBefore
+func (pp *postgresProvider) DeleteExpiredIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (_ int64, err error) {
+	defer observe("DeleteExpiredIdempotencyKeys", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.IdempotencyTable, "DeleteExpiredIdempotencyKeys", "DELETE")
+	defer tracing.End(span, &err)
+	tag, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM "%s"
+		WHERE created_at < $1;`,
+	pp.cfg.IdempotencyTable),
+	expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/metrics"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+)
+
+// observe records latency and failures of the storage method,
+// errors which are caused by the client data arent counted as failures
+func observe(method string, start time.Time, err *error) {
+	metrics.DbQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err == nil ||
+		errors.Is(*err, storage.ErrCarNotFound) ||
+		errors.Is(*err, storage.ErrCarExist) ||
+		errors.Is(*err, storage.ErrInvalidFilter) {
+		return
+	}
+	metrics.DbQueryErrors.WithLabelValues(method).Inc()
+}
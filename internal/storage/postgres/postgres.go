@@ -5,12 +5,12 @@ import (
 	"fmt"
 
 	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type postgresProvider struct {
 	cfg    config.PostgresConfig
-	dbConn *pgx.Conn
+	dbConn *pgxpool.Pool
 }
 
 func NewPostgresProvider(ctx context.Context, cfg config.PostgresConfig) (*postgresProvider, error) {
@@ -22,7 +22,14 @@ func NewPostgresProvider(ctx context.Context, cfg config.PostgresConfig) (*postg
 		cfg.Port,
 		cfg.Database,
 	)
-	conn, err := pgx.Connect(ctx, connString)
+	poolCfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgresql config: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxConns)
+	}
+	conn, err := pgxpool.ConnectConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to postgresql: %w", err)
 	}
@@ -30,4 +37,18 @@ func NewPostgresProvider(ctx context.Context, cfg config.PostgresConfig) (*postg
 		cfg:    cfg,
 		dbConn: conn,
 	}, nil
-}
\ No newline at end of file
+}
+
+// Stat returns statistics of the connection pool
+func (pp *postgresProvider) Stat() *pgxpool.Stat {
+	return pp.dbConn.Stat()
+}
+
+// Ping checks that the database is available
+func (pp *postgresProvider) Ping(ctx context.Context) error {
+	return pp.dbConn.Ping(ctx)
+}
+
+func (pp *postgresProvider) Close() {
+	pp.dbConn.Close()
+}
@@ -0,0 +1,273 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/jackc/pgx/v4"
+)
+
+// number of the cars from which they are copied to the staging table, smaller lists are sent as arrays
+const defaultCopyThreshold = 500
+
+// statuses of the saved cars returned by the save query
+const (
+	statusInserted = "inserted"
+	statusUpdated  = "updated"
+	statusSkipped  = "skipped"
+	// id of the car with these statuses is its position in the list
+	statusVinConflict = "vin_conflict"
+	statusDuplicate   = "duplicate"
+)
+
+// columns of the saved cars, ord keeps the position of the car in the list
+var stagingColumns = []string{
+	"ord", "reg_num", "mark", "model", "year", "owner_name", "owner_surname", "owner_patronymic",
+	"plate_type", "plate_series", "plate_number", "plate_region", "vin", "source", "fetched_at", "updated_by",
+}
+
+const savedColumns = `reg_num, mark, model, year, owner_name, owner_surname, owner_patronymic,
+	plate_type, plate_series, plate_number, plate_region, vin, source, fetched_at, updated_by`
+
+// SaveCars saves cars in one transaction and resolves the existing register numbers by the policy.
+// Big lists are copied to the temporary staging table, small ones are passed as arrays, after that
+// the same query moves them to the car table. If the list contains the same register number
+// several times, only the first car is saved and the others are returned as duplicates. Except the fail policy,
+// cars with the vin of the other car arent saved and returned as vin conflicts
+func (pp *postgresProvider) SaveCars(ctx context.Context, carList []models.Car, policy models.ConflictPolicy) (_ models.SaveResult, err error) {
+	defer observe("SaveCars", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "SaveCars", "INSERT")
+	defer tracing.End(span, &err)
+	result := models.SaveResult{
+		Inserted:     []int{},
+		Updated:      []int{},
+		Skipped:      []int{},
+		VinConflicts: []int{},
+		Duplicates:   []int{},
+	}
+	if len(carList) == 0 {
+		return result, nil
+	}
+	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return result, storage.ErrStartTx
+	}
+	rollback := func(err error) (models.SaveResult, error) {
+		if rErr := tx.Rollback(ctx); rErr != nil {
+			return result, storage.ErrRollbackTx
+		}
+		return result, mapConflictError(err)
+	}
+	threshold := pp.cfg.CopyThreshold
+	if threshold <= 0 {
+		threshold = defaultCopyThreshold
+	}
+	var (
+		source string
+		args   []interface{}
+	)
+	if len(carList) >= threshold {
+		source, err = pp.copyToStaging(ctx, tx, carList)
+		if err != nil {
+			return rollback(err)
+		}
+	} else {
+		source, args = carArrays(carList)
+	}
+	rows, err := tx.Query(ctx, pp.saveQuery(source, policy), args...)
+	if err != nil {
+		return rollback(err)
+	}
+	for rows.Next() {
+		var (
+			id     int
+			status string
+		)
+		if err = rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return rollback(err)
+		}
+		switch status {
+		case statusInserted:
+			result.Inserted = append(result.Inserted, id)
+		case statusUpdated:
+			result.Updated = append(result.Updated, id)
+		case statusVinConflict:
+			result.VinConflicts = append(result.VinConflicts, id)
+		case statusDuplicate:
+			result.Duplicates = append(result.Duplicates, id)
+		default:
+			result.Skipped = append(result.Skipped, id)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return rollback(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return result, storage.ErrCommitTx
+	}
+	return result, nil
+}
+
+// copyToStaging copies cars to the temporary table which is dropped with the end of the transaction
+// and returns the select of its rows
+func (pp *postgresProvider) copyToStaging(ctx context.Context, tx pgx.Tx, carList []models.Car) (string, error) {
+	staging := pp.cfg.CarTable + "_staging"
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		CREATE TEMP TABLE "%s" ON COMMIT DROP AS
+		SELECT 0 AS ord, %s
+		FROM "%s"
+		WITH NO DATA;`,
+	staging, savedColumns, pp.cfg.CarTable))
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{staging}, stagingColumns,
+		pgx.CopyFromSlice(len(carList), func(i int) ([]interface{}, error) {
+			car := carList[i]
+			plate := plateValues(car.Plate)
+			return []interface{}{
+				i, car.RegisterNumber, car.Mark, car.Model, int32(car.Year),
+				car.Owner.Name, car.Owner.Surname, car.Owner.Patronymic,
+				plate[0], plate[1], plate[2], plate[3],
+				car.Vin, carSource(car), car.FetchedAt, car.UpdatedBy,
+			}, nil
+		}))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`SELECT * FROM "%s"`, staging), nil
+}
+
+// carArrays returns the select of the cars passed as arrays and its arguments
+func carArrays(carList []models.Car) (string, []interface{}) {
+	var (
+		regNums      = make([]string, len(carList))
+		marks        = make([]string, len(carList))
+		carModels    = make([]string, len(carList))
+		years        = make([]int32, len(carList))
+		names        = make([]string, len(carList))
+		surnames     = make([]string, len(carList))
+		patronymics  = make([]*string, len(carList))
+		plateTypes   = make([]*string, len(carList))
+		plateSeries  = make([]*string, len(carList))
+		plateNumbers = make([]*string, len(carList))
+		plateRegions = make([]*string, len(carList))
+		vins         = make([]*string, len(carList))
+		sources      = make([]string, len(carList))
+		fetchedTimes = make([]*time.Time, len(carList))
+		editors      = make([]*string, len(carList))
+	)
+	for i, car := range carList {
+		regNums[i] = car.RegisterNumber
+		marks[i] = car.Mark
+		carModels[i] = car.Model
+		years[i] = int32(car.Year)
+		names[i] = car.Owner.Name
+		surnames[i] = car.Owner.Surname
+		patronymics[i] = car.Owner.Patronymic
+		plate := plateValues(car.Plate)
+		plateTypes[i], plateSeries[i], plateNumbers[i], plateRegions[i] = plate[0], plate[1], plate[2], plate[3]
+		vins[i] = car.Vin
+		sources[i] = carSource(car)
+		fetchedTimes[i] = car.FetchedAt
+		editors[i] = car.UpdatedBy
+	}
+	return fmt.Sprintf(`
+		SELECT s.ord - 1 AS ord, %s
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::int[], $5::text[], $6::text[], $7::text[],
+			$8::text[], $9::text[], $10::text[], $11::text[], $12::text[], $13::text[], $14::timestamptz[], $15::text[])
+		WITH ORDINALITY AS s(%s, ord)`,
+		savedColumns, savedColumns),
+		[]interface{}{regNums, marks, carModels, years, names, surnames, patronymics,
+			plateTypes, plateSeries, plateNumbers, plateRegions, vins, sources, fetchedTimes, editors}
+}
+
+// saveQuery moves the cars from the source to the car table and returns ids of the cars with their statuses.
+// The main query sees the table before the insert, so the joined cars are the existing ones. Cars whose vin
+// belongs to the other existing car or to the previous car of the list are excluded, except the fail policy.
+// Only the first car of the repeated register number is saved, positions of the others are returned
+func (pp *postgresProvider) saveQuery(source string, policy models.ConflictPolicy) string {
+	var conflict, skipped string
+	excluded := fmt.Sprintf(`
+			SELECT s.ord
+			FROM cars_all s
+			WHERE s.vin IS NOT NULL AND (
+				EXISTS (SELECT 1 FROM "%s" c WHERE c.vin = s.vin AND c.reg_num <> s.reg_num)
+				OR EXISTS (SELECT 1 FROM cars_all p WHERE p.vin = s.vin AND p.ord < s.ord))`,
+	pp.cfg.CarTable)
+	switch policy {
+	case models.ConflictUpdate:
+		// the vin and the plate which the saved car doesnt have are kept, so they arent wiped by the files without them
+		conflict = fmt.Sprintf(`ON CONFLICT (reg_num) DO UPDATE SET
+			mark = EXCLUDED.mark,
+			model = EXCLUDED.model,
+			year = EXCLUDED.year,
+			owner_name = EXCLUDED.owner_name,
+			owner_surname = EXCLUDED.owner_surname,
+			owner_patronymic = EXCLUDED.owner_patronymic,
+			plate_type = COALESCE(EXCLUDED.plate_type, "%[1]s".plate_type),
+			plate_series = COALESCE(EXCLUDED.plate_series, "%[1]s".plate_series),
+			plate_number = COALESCE(EXCLUDED.plate_number, "%[1]s".plate_number),
+			plate_region = COALESCE(EXCLUDED.plate_region, "%[1]s".plate_region),
+			vin = COALESCE(EXCLUDED.vin, "%[1]s".vin),
+			source = EXCLUDED.source,
+			fetched_at = EXCLUDED.fetched_at,
+			updated_at = now(),
+			updated_by = EXCLUDED.updated_by`,
+		pp.cfg.CarTable)
+	case models.ConflictFail:
+		// the violated constraint stops the save
+		excluded = "SELECT ord FROM cars_all WHERE false"
+	default:
+		conflict = "ON CONFLICT (reg_num) DO NOTHING"
+		skipped = fmt.Sprintf(`
+		UNION ALL
+		SELECT c.car_id, '%s'
+		FROM "%s" c
+		JOIN src USING (reg_num)`,
+		statusSkipped, pp.cfg.CarTable)
+	}
+	return fmt.Sprintf(`
+		WITH cars AS (%s
+		), cars_all AS (
+			SELECT DISTINCT ON (reg_num) *
+			FROM cars
+			ORDER BY reg_num, ord
+		), duplicates AS (
+			SELECT ord
+			FROM cars
+			WHERE ord NOT IN (SELECT ord FROM cars_all)
+		), vin_conflicts AS (%s
+		), src AS (
+			SELECT *
+			FROM cars_all
+			WHERE ord NOT IN (SELECT ord FROM vin_conflicts)
+		), saved AS (
+			INSERT INTO "%s" (%s)
+			SELECT %s
+			FROM src
+			ORDER BY ord
+			%s
+			RETURNING car_id, CASE WHEN xmax = 0 THEN '%s' ELSE '%s' END AS status
+		)
+		SELECT car_id, status FROM saved%s
+		UNION ALL
+		SELECT ord, '%s' FROM vin_conflicts
+		UNION ALL
+		SELECT ord, '%s' FROM duplicates;`,
+	source, excluded, pp.cfg.CarTable, savedColumns, savedColumns, conflict, statusInserted, statusUpdated, skipped,
+	statusVinConflict, statusDuplicate)
+}
+
+func carSource(car models.Car) string {
+	if car.Source == "" {
+		return models.SourceExternalApi
+	}
+	return car.Source
+}
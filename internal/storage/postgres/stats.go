@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/storage"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	"github.com/jackc/pgx/v4"
+)
+
+// aggregateExpressions are the sql expressions of the aggregates, age is counted from the current year
+var aggregateExpressions = map[string]string{
+	models.AggregateAvgAge:  "round(avg(date_part('year', now()) - year)::numeric, 1)::float8",
+	models.AggregateMinYear: "min(year)",
+	models.AggregateMaxYear: "max(year)",
+}
+
+// aggregateColumns returns the selected aggregates and the function which returns their scan targets
+func aggregateColumns(aggregates []string) (string, func(*models.Aggregates) []interface{}) {
+	var columns strings.Builder
+	for _, aggregate := range aggregates {
+		// aggregate names are checked by the handler
+		columns.WriteString(", " + aggregateExpressions[aggregate])
+	}
+	return columns.String(), func(values *models.Aggregates) []interface{} {
+		targets := make([]interface{}, 0, len(aggregates))
+		for _, aggregate := range aggregates {
+			switch aggregate {
+			case models.AggregateAvgAge:
+				targets = append(targets, &values.AvgAge)
+			case models.AggregateMinYear:
+				targets = append(targets, &values.MinYear)
+			case models.AggregateMaxYear:
+				targets = append(targets, &values.MaxYear)
+			}
+		}
+		return targets
+	}
+}
+
+// facetGroup describes the grouping of the facet, mark is empty for the dimensions except the model
+type facetGroup struct {
+	value     string
+	mark      string
+	groupBy   string
+	orderBy   string
+	condition string
+	limited   bool
+}
+
+// newFacetGroup returns the grouping of the dimension, the years are ordered by the bucket and arent limited,
+// the other values are ordered by the number of the cars
+func newFacetGroup(facet string, yearBucket int) facetGroup {
+	switch facet {
+	case models.FacetModel:
+		return facetGroup{value: "model", mark: "mark", groupBy: "mark, model", orderBy: "count(*) DESC, mark, model", limited: true}
+	case models.FacetYear:
+		if yearBucket <= 1 {
+			return facetGroup{value: "year::text", mark: "''", groupBy: "year", orderBy: "year"}
+		}
+		bucket := fmt.Sprintf("year / %[1]d * %[1]d", yearBucket)
+		return facetGroup{
+			value:   fmt.Sprintf("concat(%s, '-', %s + %d)", bucket, bucket, yearBucket-1),
+			mark:    "''",
+			groupBy: bucket,
+			orderBy: bucket,
+		}
+	case models.FacetPlateRegion:
+		return facetGroup{value: "plate_region", mark: "''", groupBy: "plate_region", orderBy: "count(*) DESC, plate_region",
+			condition: "plate_region IS NOT NULL", limited: true}
+	}
+	return facetGroup{value: "mark", mark: "''", groupBy: "mark", orderBy: "count(*) DESC, mark", limited: true}
+}
+
+// GetCarStats returns the number, aggregates and facets of the filtered cars. All queries use the same snapshot,
+// so the facets match the total
+func (pp *postgresProvider) GetCarStats(ctx context.Context, filter models.Filter, option models.StatsOption) (_ models.CarStats, err error) {
+	defer observe("GetCarStats", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.CarTable, "GetCarStats", "SELECT")
+	defer tracing.End(span, &err)
+	tx, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return models.CarStats{}, storage.ErrStartTx
+	}
+	// nothing is changed, so the transaction is only closed
+	defer tx.Rollback(ctx)
+	where, usedData := filterCondition(filter, 0)
+	if where == "" {
+		where = "true"
+	}
+	where = "(" + where + ")"
+	columns, targets := aggregateColumns(option.Aggregates)
+	stats := models.CarStats{
+		Facets: make(map[string][]models.FacetValue, len(option.Facets)),
+	}
+	row := tx.QueryRow(ctx, fmt.Sprintf(`SELECT count(*)%s FROM "%s" WHERE %s`, columns, pp.cfg.CarTable, where), usedData...)
+	if err = row.Scan(append([]interface{}{&stats.Total}, targets(&stats.Aggregates)...)...); err != nil {
+		return models.CarStats{}, mapFilterError(err)
+	}
+	for _, facet := range option.Facets {
+		group := newFacetGroup(facet, option.YearBucket)
+		condition := where
+		if group.condition != "" {
+			condition += " AND " + group.condition
+		}
+		query := fmt.Sprintf(`SELECT %s, %s, count(*)%s FROM "%s" WHERE %s GROUP BY %s ORDER BY %s`,
+			group.value, group.mark, columns, pp.cfg.CarTable, condition, group.groupBy, group.orderBy)
+		if group.limited && option.Limit > 0 {
+			query += fmt.Sprintf(" LIMIT %d", option.Limit)
+		}
+		values, err := scanFacetValues(ctx, tx, query, usedData, targets)
+		if err != nil {
+			return models.CarStats{}, err
+		}
+		stats.Facets[facet] = values
+	}
+	return stats, nil
+}
+
+func scanFacetValues(ctx context.Context, tx pgx.Tx, query string, usedData []interface{}, targets func(*models.Aggregates) []interface{}) ([]models.FacetValue, error) {
+	rows, err := tx.Query(ctx, query, usedData...)
+	if err != nil {
+		return nil, mapFilterError(err)
+	}
+	defer rows.Close()
+	values := []models.FacetValue{}
+	for rows.Next() {
+		var value models.FacetValue
+		if err := rows.Scan(append([]interface{}{&value.Value, &value.Mark, &value.Count}, targets(&value.Aggregates)...)...); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapFilterError(err)
+	}
+	return values, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestNewFacetGroup(t *testing.T) {
+	tests := []struct {
+		facet      string
+		yearBucket int
+		want       facetGroup
+	}{
+		{
+			facet: models.FacetMark,
+			want:  facetGroup{value: "mark", mark: "''", groupBy: "mark", orderBy: "count(*) DESC, mark", limited: true},
+		},
+		{
+			facet: models.FacetModel,
+			want:  facetGroup{value: "model", mark: "mark", groupBy: "mark, model", orderBy: "count(*) DESC, mark, model", limited: true},
+		},
+		{
+			facet:      models.FacetYear,
+			yearBucket: 1,
+			want:       facetGroup{value: "year::text", mark: "''", groupBy: "year", orderBy: "year"},
+		},
+		{
+			facet: models.FacetYear,
+			want:  facetGroup{value: "year::text", mark: "''", groupBy: "year", orderBy: "year"},
+		},
+		{
+			facet:      models.FacetYear,
+			yearBucket: 5,
+			want: facetGroup{
+				value:   "concat(year / 5 * 5, '-', year / 5 * 5 + 4)",
+				mark:    "''",
+				groupBy: "year / 5 * 5",
+				orderBy: "year / 5 * 5",
+			},
+		},
+		{
+			facet: models.FacetPlateRegion,
+			want: facetGroup{value: "plate_region", mark: "''", groupBy: "plate_region", orderBy: "count(*) DESC, plate_region",
+				condition: "plate_region IS NOT NULL", limited: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.facet, func(t *testing.T) {
+			if got := newFacetGroup(tt.facet, tt.yearBucket); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newFacetGroup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateColumns(t *testing.T) {
+	tests := []struct {
+		name        string
+		aggregates  []string
+		wantColumns string
+	}{
+		{name: "none", wantColumns: ""},
+		{
+			name:        "age",
+			aggregates:  []string{models.AggregateAvgAge},
+			wantColumns: ", " + aggregateExpressions[models.AggregateAvgAge],
+		},
+		{
+			name:        "years in the request order",
+			aggregates:  []string{models.AggregateMaxYear, models.AggregateMinYear},
+			wantColumns: ", max(year), min(year)",
+		},
+		{
+			name:        "all",
+			aggregates:  []string{models.AggregateMinYear, models.AggregateAvgAge, models.AggregateMaxYear},
+			wantColumns: ", min(year), " + aggregateExpressions[models.AggregateAvgAge] + ", max(year)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, targets := aggregateColumns(tt.aggregates)
+			if columns != tt.wantColumns {
+				t.Errorf("aggregateColumns() columns = %q, want %q", columns, tt.wantColumns)
+			}
+			var values models.Aggregates
+			got := targets(&values)
+			if len(got) != len(tt.aggregates) {
+				t.Fatalf("aggregateColumns() returns %d targets, want %d", len(got), len(tt.aggregates))
+			}
+			// the targets are in the order of the columns
+			for i, aggregate := range tt.aggregates {
+				var want interface{}
+				switch aggregate {
+				case models.AggregateAvgAge:
+					want = &values.AvgAge
+				case models.AggregateMinYear:
+					want = &values.MinYear
+				case models.AggregateMaxYear:
+					want = &values.MaxYear
+				}
+				if got[i] != want {
+					t.Errorf("target %d isnt the field of %s", i, aggregate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+)
+
+// likeEscaper escapes the special symbols of LIKE, so the prefix is matched as is
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetSuggestions returns the most used values of the field which start with the prefix.
+// The values are read from the materialized view, so the latest changes arent seen until its refresh
+func (pp *postgresProvider) GetSuggestions(ctx context.Context, query models.SuggestQuery) (_ []models.Suggestion, err error) {
+	defer observe("GetSuggestions", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.SuggestView, "GetSuggestions", "SELECT")
+	defer tracing.End(span, &err)
+	// the pattern is passed ready, so the prefix index can be used
+	condition := "field = $1 AND lower(value::text) LIKE $2"
+	usedData := []interface{}{query.Field, strings.ToLower(likeEscaper.Replace(query.Prefix)) + "%"}
+	if query.Mark != "" {
+		condition += " AND lower(mark) = lower($3)"
+		usedData = append(usedData, query.Mark)
+	}
+	usedData = append(usedData, query.Limit)
+	// models of the different marks can have the same name, their counts are summed
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+		SELECT value, sum(count)::integer
+		FROM "%s"
+		WHERE %s
+		GROUP BY value
+		ORDER BY sum(count) DESC, value
+		LIMIT $%d;`,
+	pp.cfg.SuggestView, condition, len(usedData)),
+	usedData...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	suggestions := []models.Suggestion{}
+	for rows.Next() {
+		var suggestion models.Suggestion
+		if err = rows.Scan(&suggestion.Value, &suggestion.Count); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// RefreshSuggestions recalculates the materialized view of the suggested values without blocking its readers
+func (pp *postgresProvider) RefreshSuggestions(ctx context.Context) (err error) {
+	defer observe("RefreshSuggestions", time.Now(), &err)
+	ctx, span := pp.startSpan(ctx, pp.cfg.SuggestView, "RefreshSuggestions", "REFRESH")
+	defer tracing.End(span, &err)
+	_, err = pp.dbConn.Exec(ctx, fmt.Sprintf(`REFRESH MATERIALIZED VIEW CONCURRENTLY "%s"`, pp.cfg.SuggestView))
+	return err
+}
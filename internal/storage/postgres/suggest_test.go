@@ -0,0 +1,21 @@
+package postgres
+
+import "testing"
+
+func TestLikeEscaper(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"Lada", "Lada"},
+		{"100%", `100\%`},
+		{"a_b", `a\_b`},
+		{`C:\`, `C:\\`},
+		{`%_\`, `\%\_\\`},
+	}
+	for _, tt := range tests {
+		if got := likeEscaper.Replace(tt.prefix); got != tt.want {
+			t.Errorf("likeEscaper.Replace(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
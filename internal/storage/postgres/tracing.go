@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan creates client span of the storage method
+func (pp *postgresProvider) startSpan(ctx context.Context, table, method, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "postgres."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBName(pp.cfg.Database),
+			semconv.DBSQLTable(table),
+			semconv.DBOperation(operation),
+		),
+	)
+}
@@ -0,0 +1,86 @@
+package validator
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+var (
+	ErrPlateLetters = errors.New("plate contains not allowed letters")
+	ErrPlateFormat  = errors.New("unknown plate format")
+	ErrPlateRegion  = errors.New("unknown region code")
+	ErrPlateNumber  = errors.New("plate number cant be zero")
+)
+
+// latin letters which look like the allowed cyrillic ones
+var latinPlateLetters = strings.NewReplacer(
+	"A", "А", "B", "В", "E", "Е", "K", "К", "M", "М", "H", "Н",
+	"O", "О", "P", "Р", "C", "С", "T", "Т", "Y", "У", "X", "Х",
+)
+
+const plateLetters = "АВЕКМНОРСТУХ"
+
+// formats of the plates after the letters are replaced by the cyrillic ones
+var (
+	privatePlate    = regexp.MustCompile(`^([` + plateLetters + `])(\d{3})([` + plateLetters + `]{2})(\d{2,3})$`)
+	taxiPlate       = regexp.MustCompile(`^([` + plateLetters + `]{2})(\d{3})(\d{2,3})$`)
+	trailerPlate    = regexp.MustCompile(`^([` + plateLetters + `]{2})(\d{4})(\d{2,3})$`)
+	motorcyclePlate = regexp.MustCompile(`^(\d{4})([` + plateLetters + `]{2})(\d{2,3})$`)
+	// mission code, CD letters and one digit or D/T letter and three digits
+	diplomaticPlate = regexp.MustCompile(`^(\d{3})(?:(СD)(\d)|(D|Т)(\d{3}))(\d{2,3})$`)
+)
+
+// parsePlate parses russian register number of the private car, taxi, trailer, motorcycle, diplomatic or military car.
+// Spaces are ignored, latin letters which look like the allowed cyrillic ones are accepted.
+// Text of the taxi and trailer plates can be the same, the first format with the known region code is used,
+// so taxi is preferred. Military plates have the same text as the motorcycle ones, they are recognized by the military codes
+func parsePlate(regNum string, militaryCodes map[string]struct{}) (models.Plate, error) {
+	value := latinPlateLetters.Replace(strings.ToUpper(strings.Join(strings.Fields(regNum), "")))
+	for _, r := range value {
+		if !(r >= '0' && r <= '9') && r != 'D' && !strings.ContainsRune(plateLetters, r) {
+			return models.Plate{}, ErrPlateLetters
+		}
+	}
+	var candidates []models.Plate
+	if parts := privatePlate.FindStringSubmatch(value); parts != nil {
+		candidates = append(candidates, models.Plate{Type: models.PlateTypePrivate, Series: parts[1] + parts[3], Number: parts[2], Region: parts[4]})
+	}
+	if parts := taxiPlate.FindStringSubmatch(value); parts != nil {
+		candidates = append(candidates, models.Plate{Type: models.PlateTypeTaxi, Series: parts[1], Number: parts[2], Region: parts[3]})
+	}
+	if parts := trailerPlate.FindStringSubmatch(value); parts != nil {
+		candidates = append(candidates, models.Plate{Type: models.PlateTypeTrailer, Series: parts[1], Number: parts[2], Region: parts[3]})
+	}
+	if parts := motorcyclePlate.FindStringSubmatch(value); parts != nil {
+		plate := models.Plate{Type: models.PlateTypeMotorcycle, Series: parts[2], Number: parts[1], Region: parts[3]}
+		if _, ok := militaryCodes[plate.Region]; ok {
+			plate.Type = models.PlateTypeMilitary
+			return plate, checkPlateNumber(plate)
+		}
+		candidates = append(candidates, plate)
+	}
+	if parts := diplomaticPlate.FindStringSubmatch(value); parts != nil {
+		// diplomatic letters are latin ones
+		series := strings.NewReplacer("С", "C", "Т", "T").Replace(parts[2] + parts[4])
+		candidates = append(candidates, models.Plate{Type: models.PlateTypeDiplomatic, Series: series, Number: parts[1] + "-" + parts[3] + parts[5], Region: parts[6]})
+	}
+	if len(candidates) == 0 {
+		return models.Plate{}, ErrPlateFormat
+	}
+	for _, plate := range candidates {
+		if _, ok := plateRegions[plate.Region]; ok {
+			return plate, checkPlateNumber(plate)
+		}
+	}
+	return models.Plate{}, ErrPlateRegion
+}
+
+func checkPlateNumber(plate models.Plate) error {
+	if plate.Type != models.PlateTypeDiplomatic && strings.Trim(plate.Number, "0") == "" {
+		return ErrPlateNumber
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestParsePlate(t *testing.T) {
+	militaryCodes := map[string]struct{}{"34": {}}
+	tests := []struct {
+		regNum  string
+		want    models.Plate
+		wantErr error
+	}{
+		{regNum: "А123ВС77", want: models.Plate{Type: models.PlateTypePrivate, Series: "АВС", Number: "123", Region: "77"}},
+		{regNum: "x123xx 150", want: models.Plate{Type: models.PlateTypePrivate, Series: "ХХХ", Number: "123", Region: "150"}},
+		{regNum: "АВ12377", want: models.Plate{Type: models.PlateTypeTaxi, Series: "АВ", Number: "123", Region: "77"}},
+		{regNum: "АВ123477", want: models.Plate{Type: models.PlateTypeTrailer, Series: "АВ", Number: "1234", Region: "77"}},
+		{regNum: "1234АВ77", want: models.Plate{Type: models.PlateTypeMotorcycle, Series: "АВ", Number: "1234", Region: "77"}},
+		{regNum: "1234АВ34", want: models.Plate{Type: models.PlateTypeMilitary, Series: "АВ", Number: "1234", Region: "34"}},
+		{regNum: "001CD177", want: models.Plate{Type: models.PlateTypeDiplomatic, Series: "CD", Number: "001-1", Region: "77"}},
+		{regNum: "001 D 123 77", want: models.Plate{Type: models.PlateTypeDiplomatic, Series: "D", Number: "001-123", Region: "77"}},
+		{regNum: "Б123ВС77", wantErr: ErrPlateLetters},
+		{regNum: "А123ВС00", wantErr: ErrPlateRegion},
+		{regNum: "А000ВС77", wantErr: ErrPlateNumber},
+		{regNum: "А12ВС77", wantErr: ErrPlateFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.regNum, func(t *testing.T) {
+			got, err := parsePlate(tt.regNum, militaryCodes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parsePlate(%s) error = %v, want %v", tt.regNum, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != tt.want {
+				t.Errorf("parsePlate(%s) = %+v, want %+v", tt.regNum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlateText(t *testing.T) {
+	tests := []struct {
+		regNums []string
+		want    string
+	}{
+		{regNums: []string{"X123XX150", "х123хх 150", "Х123ХХ150"}, want: "Х123ХХ150"},
+		{regNums: []string{"ab12377", "АВ 123 77"}, want: "АВ12377"},
+		{regNums: []string{"ab123477"}, want: "АВ123477"},
+		{regNums: []string{"1234 ab 77"}, want: "1234АВ77"},
+		{regNums: []string{"001 cd 1 77", "001CD177"}, want: "001CD177"},
+		{regNums: []string{"001 t 123 77"}, want: "001T12377"},
+	}
+	for _, tt := range tests {
+		for _, regNum := range tt.regNums {
+			plate, err := parsePlate(regNum, nil)
+			if err != nil {
+				t.Fatalf("parsePlate(%s) error = %v", regNum, err)
+			}
+			if got := plate.Text(); got != tt.want {
+				t.Errorf("text of %s = %s, want %s", regNum, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+const minCarYear = 1900
+
+// fields which are NOT NULL in the storage, they are always required
+var requiredCarFields = map[string]struct{}{
+	"regNum":        {},
+	"mark":          {},
+	"model":         {},
+	"year":          {},
+	"owner.name":    {},
+	"owner.surname": {},
+}
+
+// fieldRule is the compiled rule of the field
+type fieldRule struct {
+	field     string
+	required  bool
+	regex     *regexp.Regexp
+	minLength int
+	maxLength int
+	enum      []string
+	min       *int
+	max       *int
+	plate     bool
+	vin       bool
+}
+
+type carValidator struct {
+	rules         map[string]fieldRule
+	militaryCodes map[string]struct{}
+}
+
+// New compiles rules of the car fields, year is limited by 1900 and the current year if the rule doesnt set the range
+func New(validCfg config.ValidatorConfig) (*carValidator, error) {
+	cv := &carValidator{
+		rules:         make(map[string]fieldRule, len(config.ValidatedCarFields)),
+		militaryCodes: make(map[string]struct{}, len(validCfg.PlateMilitaryCodes)),
+	}
+	for _, code := range validCfg.PlateMilitaryCodes {
+		cv.militaryCodes[code] = struct{}{}
+	}
+	for _, field := range config.ValidatedCarFields {
+		cfgRule := validCfg.Rules[field]
+		rule := fieldRule{
+			field:     field,
+			required:  cfgRule.Required,
+			minLength: cfgRule.MinLength,
+			maxLength: cfgRule.MaxLength,
+			enum:      cfgRule.Enum,
+			min:       cfgRule.Min,
+			max:       cfgRule.Max,
+			plate:     cfgRule.Plate,
+			// vin is always checked by ISO 3779
+			vin: field == "vin",
+		}
+		if _, ok := requiredCarFields[field]; ok {
+			rule.required = true
+		}
+		if pattern := validCfg.FieldRegex(field); pattern != "" {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect regex of %s: %w", field, err)
+			}
+			rule.regex = regex
+		}
+		if field == "year" && rule.min == nil {
+			minYear := minCarYear
+			rule.min = &minYear
+		}
+		cv.rules[field] = rule
+	}
+	return cv, nil
+}
+
+// ValidateCar checks all fields of the full car record and returns all found errors
+func (cv *carValidator) ValidateCar(car models.Car) []models.FieldError {
+	var errs []models.FieldError
+	errs = append(errs, cv.checkString("regNum", &car.RegisterNumber)...)
+	errs = append(errs, cv.checkString("mark", &car.Mark)...)
+	errs = append(errs, cv.checkString("model", &car.Model)...)
+	var year *uint16
+	if car.Year != 0 {
+		year = &car.Year
+	}
+	errs = append(errs, cv.checkYear(year)...)
+	owner := car.Owner
+	if owner == nil {
+		owner = &models.Owner{}
+	}
+	errs = append(errs, cv.checkString("owner.name", &owner.Name)...)
+	errs = append(errs, cv.checkString("owner.surname", &owner.Surname)...)
+	errs = append(errs, cv.checkString("owner.patronymic", owner.Patronymic)...)
+	errs = append(errs, cv.checkString("vin", car.Vin)...)
+	return errs
+}
+
+// ValidateCarPatch checks only changed fields of the new car data and returns all found errors
+func (cv *carValidator) ValidateCarPatch(newData models.CarForPatch) []models.FieldError {
+	var errs []models.FieldError
+	check := func(field string, value *string) {
+		if value != nil {
+			errs = append(errs, cv.checkString(field, value)...)
+		}
+	}
+	check("regNum", newData.RegisterNumber)
+	check("mark", newData.Mark)
+	check("model", newData.Model)
+	if newData.Year != nil {
+		errs = append(errs, cv.checkYear(newData.Year)...)
+	}
+	if newData.ClearVin {
+		errs = append(errs, cv.checkString("vin", nil)...)
+	}
+	check("vin", newData.Vin)
+	if newData.Owner == nil {
+		return errs
+	}
+	check("owner.name", newData.Owner.Name)
+	check("owner.surname", newData.Owner.Surname)
+	if newData.Owner.ClearPatronymic {
+		errs = append(errs, cv.checkString("owner.patronymic", nil)...)
+	}
+	check("owner.patronymic", newData.Owner.Patronymic)
+	return errs
+}
+
+// ParsePlate returns components of the russian registration plate
+func (cv *carValidator) ParsePlate(regNum string) (models.Plate, error) {
+	return parsePlate(regNum, cv.militaryCodes)
+}
+
+// DecodeVin returns manufacturer, region and model year of the vin
+func (cv *carValidator) DecodeVin(vin string) (models.VinInfo, error) {
+	return decodeVin(vin, time.Now())
+}
+
+// ValidateField checks the single string field, empty value is the missing one
+func (cv *carValidator) ValidateField(field, value string) []models.FieldError {
+	return cv.checkString(field, &value)
+}
+
+func (cv *carValidator) checkString(field string, value *string) []models.FieldError {
+	rule := cv.rules[field]
+	if value == nil || *value == "" {
+		if rule.required {
+			return []models.FieldError{rule.error(models.RuleRequired, "%s is required", field)}
+		}
+		return nil
+	}
+	var errs []models.FieldError
+	length := utf8.RuneCountInString(*value)
+	if rule.minLength > 0 && length < rule.minLength {
+		errs = append(errs, rule.error(models.RuleMinLength, "%s must be at least %d characters", field, rule.minLength))
+	}
+	if rule.maxLength > 0 && length > rule.maxLength {
+		errs = append(errs, rule.error(models.RuleMaxLength, "%s must be at most %d characters", field, rule.maxLength))
+	}
+	if len(rule.enum) != 0 && !contains(rule.enum, *value) {
+		errs = append(errs, rule.error(models.RuleEnum, "%s must be one of %s", field, strings.Join(rule.enum, ", ")))
+	}
+	if rule.regex != nil && !rule.regex.MatchString(*value) {
+		errs = append(errs, rule.error(models.RuleRegex, "%s doesnt match the pattern", field))
+	}
+	if rule.plate {
+		if _, err := cv.ParsePlate(*value); err != nil {
+			errs = append(errs, rule.error(models.RulePlate, "%s is not valid plate: %s", field, err.Error()))
+		}
+	}
+	if rule.vin {
+		if err := checkVin(*value); err != nil {
+			errs = append(errs, rule.error(models.RuleVin, "%s is not valid: %s", field, err.Error()))
+		}
+	}
+	return errs
+}
+
+// checkYear uses the current year as max by default, so the long running service doesnt need restart
+func (cv *carValidator) checkYear(year *uint16) []models.FieldError {
+	rule := cv.rules["year"]
+	if year == nil {
+		if rule.required {
+			return []models.FieldError{rule.error(models.RuleRequired, "year is required")}
+		}
+		return nil
+	}
+	max := time.Now().Year()
+	if rule.max != nil {
+		max = *rule.max
+	}
+	switch {
+	case rule.min != nil && int(*year) < *rule.min:
+		return []models.FieldError{rule.error(models.RuleMin, "year must be at least %d", *rule.min)}
+	case int(*year) > max:
+		return []models.FieldError{rule.error(models.RuleMax, "year must be at most %d", max)}
+	}
+	return nil
+}
+
+func (fr fieldRule) error(ruleName, format string, args ...interface{}) models.FieldError {
+	return models.FieldError{
+		Field:   fr.field,
+		Rule:    ruleName,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
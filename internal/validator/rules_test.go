@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/config"
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestCarValidator(t *testing.T) {
+	maxYear := 2020
+	patronymic := "Ivanovich"
+	cv, err := New(config.ValidatorConfig{
+		RegisterNumberRegex: "^[A-Z][0-9]{3}[A-Z]{2}[0-9]{2,3}$",
+		Rules: map[string]config.FieldRule{
+			"mark":             {Enum: []string{"Lada", "Volga"}},
+			"model":            {MaxLength: 5},
+			"year":             {Max: &maxYear},
+			"owner.patronymic": {Required: true, Regex: "^[A-Z][a-z]+$"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := func(errs []models.FieldError) []string {
+		var got []string
+		for _, fieldErr := range errs {
+			got = append(got, fieldErr.Field+":"+fieldErr.Rule)
+		}
+		return got
+	}
+	car := models.Car{
+		RegisterNumber: "X123XX150",
+		Mark:           "Lada",
+		Model:          "Vesta",
+		Year:           2002,
+		Owner:          &models.Owner{Name: "Ivan", Surname: "Ivanov", Patronymic: &patronymic},
+	}
+	if errs := cv.ValidateCar(car); len(errs) != 0 {
+		t.Errorf("ValidateCar(valid car) = %v, want no errors", errs)
+	}
+	car = models.Car{RegisterNumber: "bad", Mark: "BMW", Model: "Granta", Year: 2021}
+	want := []string{
+		"regNum:regex",
+		"mark:enum",
+		"model:max_length",
+		"year:max",
+		"owner.name:required",
+		"owner.surname:required",
+		"owner.patronymic:required",
+	}
+	if got := rules(cv.ValidateCar(car)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateCar(not valid car) = %v, want %v", got, want)
+	}
+	year := uint16(1800)
+	patch := models.CarForPatch{
+		Year:  &year,
+		Owner: &models.OwnerForPatch{ClearPatronymic: true},
+	}
+	want = []string{"year:min", "owner.patronymic:required"}
+	if got := rules(cv.ValidateCarPatch(patch)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateCarPatch() = %v, want %v", got, want)
+	}
+}
+
+func TestNewWithWrongRegex(t *testing.T) {
+	if _, err := New(config.ValidatorConfig{MarkRegex: "("}); err == nil {
+		t.Error("expected error of the wrong regex")
+	}
+}
@@ -1,8 +1,19 @@
 package validator
 
-import "regexp"
+import (
+	"regexp"
+	"sync"
+)
 
+// compiled patterns of ValideteByRegex
+var regexCache sync.Map
+
+// ValideteByRegex matches the string by the pattern, the pattern is compiled only once
 func ValideteByRegex(str, patern string) bool {
+	if regex, ok := regexCache.Load(patern); ok {
+		return regex.(*regexp.Regexp).MatchString(str)
+	}
 	regex := regexp.MustCompile(patern)
+	regexCache.Store(patern, regex)
 	return regex.MatchString(str)
-}
\ No newline at end of file
+}
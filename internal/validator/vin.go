@@ -0,0 +1,155 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+var (
+	ErrVinLength     = errors.New("vin must contain 17 symbols")
+	ErrVinSymbols    = errors.New("vin contains not allowed symbols")
+	ErrVinCheckDigit = errors.New("wrong vin check digit")
+)
+
+const vinLength = 17
+
+// values of the vin symbols used by the check digit, I, O and Q arent allowed
+var vinValues = map[rune]int{
+	'0': 0, '1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8, '9': 9,
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+var vinWeights = [vinLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// symbols of the model year starting from 1980, the sequence is repeated every 30 years
+const vinYearSymbols = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+const (
+	vinFirstYear  = 1980
+	vinYearsCycle = 30
+)
+
+// manufacturers of the well known WMI
+var vinManufacturers = map[string]string{
+	"XTA": "Lada",
+	"XTT": "UAZ",
+	"X96": "GAZ",
+	"X7L": "Renault Russia",
+	"XW8": "Volkswagen Russia",
+	"Z8N": "Nissan Russia",
+	"Z94": "Hyundai Russia",
+	"WVW": "Volkswagen",
+	"WV1": "Volkswagen Commercial Vehicles",
+	"WV2": "Volkswagen Commercial Vehicles",
+	"WAU": "Audi",
+	"WBA": "BMW",
+	"WDB": "Mercedes-Benz",
+	"WDD": "Mercedes-Benz",
+	"WP0": "Porsche",
+	"VF1": "Renault",
+	"VF3": "Peugeot",
+	"VF7": "Citroen",
+	"ZFA": "Fiat",
+	"SAL": "Land Rover",
+	"SAJ": "Jaguar",
+	"YV1": "Volvo",
+	"TMB": "Skoda",
+	"VSS": "SEAT",
+	"JHM": "Honda",
+	"JTD": "Toyota",
+	"JN1": "Nissan",
+	"JM1": "Mazda",
+	"JF1": "Subaru",
+	"JA3": "Mitsubishi",
+	"KMH": "Hyundai",
+	"KNA": "Kia",
+	"1FA": "Ford",
+	"1FT": "Ford",
+	"1G1": "Chevrolet",
+	"1HG": "Honda USA",
+	"4T1": "Toyota USA",
+	"5YJ": "Tesla",
+	"2T1": "Toyota Canada",
+	"3VW": "Volkswagen Mexico",
+}
+
+// checkVin checks the vin by ISO 3779, check digit is required only for the north american vins
+func checkVin(vin string) error {
+	vin = strings.ToUpper(vin)
+	if len(vin) != vinLength {
+		return ErrVinLength
+	}
+	sum := 0
+	for i, r := range vin {
+		value, ok := vinValues[r]
+		if !ok {
+			return ErrVinSymbols
+		}
+		sum += value * vinWeights[i]
+	}
+	if vinRegion(vin) != models.VinRegionNorthAmerica {
+		return nil
+	}
+	checkDigit := byte('0' + sum%11)
+	if sum%11 == 10 {
+		checkDigit = 'X'
+	}
+	if vin[8] != checkDigit {
+		return ErrVinCheckDigit
+	}
+	return nil
+}
+
+// decodeVin returns manufacturer, region and model year of the valid vin.
+// Model year symbols are repeated every 30 years, north american vins use letter at the 7th position
+// for the years from 2010, for other vins the latest year which isnt later than the next year is used
+func decodeVin(vin string, now time.Time) (models.VinInfo, error) {
+	if err := checkVin(vin); err != nil {
+		return models.VinInfo{}, err
+	}
+	vin = strings.ToUpper(vin)
+	info := models.VinInfo{
+		Wmi:          vin[:3],
+		Manufacturer: vinManufacturers[vin[:3]],
+		Region:       vinRegion(vin),
+	}
+	pos := strings.IndexByte(vinYearSymbols, vin[9])
+	if pos < 0 {
+		return info, nil
+	}
+	year := vinFirstYear + pos
+	if info.Region == models.VinRegionNorthAmerica {
+		if vin[6] < '0' || vin[6] > '9' {
+			year += vinYearsCycle
+		}
+	} else {
+		for year+vinYearsCycle <= now.Year()+1 {
+			year += vinYearsCycle
+		}
+	}
+	info.ModelYear = &year
+	return info, nil
+}
+
+func vinRegion(vin string) string {
+	switch first := vin[0]; {
+	case first >= 'A' && first <= 'H':
+		return models.VinRegionAfrica
+	case first >= 'J' && first <= 'R':
+		return models.VinRegionAsia
+	case first >= 'S' && first <= 'Z':
+		return models.VinRegionEurope
+	case first >= '1' && first <= '5':
+		return models.VinRegionNorthAmerica
+	case first == '6' || first == '7':
+		return models.VinRegionOceania
+	case first == '8' || first == '9':
+		return models.VinRegionSouthAmerica
+	}
+	return ""
+}
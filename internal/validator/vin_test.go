@@ -0,0 +1,47 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/EffectiveMobile_assignment/internal/domain/models"
+)
+
+func TestDecodeVin(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		vin     string
+		want    models.VinInfo
+		year    int
+		wantErr error
+	}{
+		{vin: "1HGCM82633A004352", want: models.VinInfo{Wmi: "1HG", Manufacturer: "Honda USA", Region: models.VinRegionNorthAmerica}, year: 2003},
+		{vin: "1hgcm82633a004352", want: models.VinInfo{Wmi: "1HG", Manufacturer: "Honda USA", Region: models.VinRegionNorthAmerica}, year: 2003},
+		{vin: "WVWZZZ1JZXW000001", want: models.VinInfo{Wmi: "WVW", Manufacturer: "Volkswagen", Region: models.VinRegionEurope}, year: 1999},
+		{vin: "XTA21099043576182", want: models.VinInfo{Wmi: "XTA", Manufacturer: "Lada", Region: models.VinRegionEurope}, year: 2004},
+		{vin: "JMZBK14Z0Z1234567", want: models.VinInfo{Wmi: "JMZ", Region: models.VinRegionAsia}},
+		{vin: "1HGCM82634A004352", wantErr: ErrVinCheckDigit},
+		{vin: "1HGCM82633A00435", wantErr: ErrVinLength},
+		{vin: "1HGCM82633A00435O", wantErr: ErrVinSymbols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.vin, func(t *testing.T) {
+			got, err := decodeVin(tt.vin, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decodeVin(%s) error = %v, want %v", tt.vin, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			year := 0
+			if got.ModelYear != nil {
+				year = *got.ModelYear
+			}
+			got.ModelYear = nil
+			if got != tt.want || year != tt.year {
+				t.Errorf("decodeVin(%s) = %+v with year %d, want %+v with year %d", tt.vin, got, year, tt.want, tt.year)
+			}
+		})
+	}
+}
@@ -32,12 +32,103 @@ CREATE TABLE public.car_table (
     year integer NOT NULL,
     owner_name character varying NOT NULL,
     owner_surname character varying NOT NULL,
-    owner_patronymic character varying
+    owner_patronymic character varying,
+    plate_type character varying,
+    plate_series character varying,
+    plate_number character varying,
+    plate_region character varying,
+    vin character varying,
+    source character varying DEFAULT 'external_api'::character varying NOT NULL,
+    fetched_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_by character varying
 );
 
 
 ALTER TABLE public.car_table OWNER TO "user";
 
+--
+-- Name: idempotency_table; Type: TABLE; Schema: public; Owner: user
+--
+
+CREATE TABLE public.idempotency_table (
+    scope character varying NOT NULL,
+    idempotency_key character varying NOT NULL,
+    request_hash character varying NOT NULL,
+    status integer DEFAULT 0 NOT NULL,
+    content_type character varying,
+    body bytea,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.idempotency_table OWNER TO "user";
+
+--
+-- Name: mark_table; Type: TABLE; Schema: public; Owner: user
+--
+
+CREATE TABLE public.mark_table (
+    mark_id integer NOT NULL,
+    name character varying NOT NULL,
+    aliases character varying[] DEFAULT '{}'::character varying[] NOT NULL
+);
+
+
+ALTER TABLE public.mark_table OWNER TO "user";
+
+--
+-- Name: model_table; Type: TABLE; Schema: public; Owner: user
+--
+
+CREATE TABLE public.model_table (
+    model_id integer NOT NULL,
+    mark_id integer NOT NULL,
+    name character varying NOT NULL,
+    aliases character varying[] DEFAULT '{}'::character varying[] NOT NULL
+);
+
+
+ALTER TABLE public.model_table OWNER TO "user";
+
+--
+-- Name: car_suggest_view; Type: MATERIALIZED VIEW; Schema: public; Owner: user
+--
+
+CREATE MATERIALIZED VIEW public.car_suggest_view AS
+ SELECT 'mark'::text AS field,
+    ''::character varying AS mark,
+    car_table.mark AS value,
+    count(*) AS count
+   FROM public.car_table
+  GROUP BY car_table.mark
+UNION ALL
+ SELECT 'model'::text AS field,
+    car_table.mark,
+    car_table.model AS value,
+    count(*) AS count
+   FROM public.car_table
+  GROUP BY car_table.mark, car_table.model
+UNION ALL
+ SELECT 'owner_name'::text AS field,
+    ''::character varying AS mark,
+    car_table.owner_name AS value,
+    count(*) AS count
+   FROM public.car_table
+  GROUP BY car_table.owner_name
+UNION ALL
+ SELECT 'owner_surname'::text AS field,
+    ''::character varying AS mark,
+    car_table.owner_surname AS value,
+    count(*) AS count
+   FROM public.car_table
+  GROUP BY car_table.owner_surname
+  WITH NO DATA;
+
+
+ALTER MATERIALIZED VIEW public.car_suggest_view OWNER TO "user";
+
 --
 -- Name: car_table_car_id_seq; Type: SEQUENCE; Schema: public; Owner: user
 --
@@ -52,6 +143,34 @@ ALTER TABLE public.car_table ALTER COLUMN car_id ADD GENERATED BY DEFAULT AS IDE
 );
 
 
+--
+-- Name: mark_table_mark_id_seq; Type: SEQUENCE; Schema: public; Owner: user
+--
+
+ALTER TABLE public.mark_table ALTER COLUMN mark_id ADD GENERATED BY DEFAULT AS IDENTITY (
+    SEQUENCE NAME public.mark_table_mark_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: model_table_model_id_seq; Type: SEQUENCE; Schema: public; Owner: user
+--
+
+ALTER TABLE public.model_table ALTER COLUMN model_id ADD GENERATED BY DEFAULT AS IDENTITY (
+    SEQUENCE NAME public.model_table_model_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
 --
 -- Name: car_table car_tabble_pkey; Type: CONSTRAINT; Schema: public; Owner: user
 --
@@ -68,6 +187,111 @@ ALTER TABLE ONLY public.car_table
     ADD CONSTRAINT reg_num_uniq UNIQUE (reg_num);
 
 
+--
+-- Name: car_table vin_uniq; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.car_table
+    ADD CONSTRAINT vin_uniq UNIQUE (vin);
+
+
+--
+-- Name: idempotency_table idempotency_table_pkey; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.idempotency_table
+    ADD CONSTRAINT idempotency_table_pkey PRIMARY KEY (scope, idempotency_key);
+
+
+--
+-- Name: mark_table mark_table_pkey; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.mark_table
+    ADD CONSTRAINT mark_table_pkey PRIMARY KEY (mark_id);
+
+
+--
+-- Name: mark_table mark_name_uniq; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.mark_table
+    ADD CONSTRAINT mark_name_uniq UNIQUE (name);
+
+
+--
+-- Name: model_table model_table_pkey; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.model_table
+    ADD CONSTRAINT model_table_pkey PRIMARY KEY (model_id);
+
+
+--
+-- Name: model_table model_name_uniq; Type: CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.model_table
+    ADD CONSTRAINT model_name_uniq UNIQUE (mark_id, name);
+
+
+--
+-- Name: model_table model_table_mark_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: user
+--
+
+ALTER TABLE ONLY public.model_table
+    ADD CONSTRAINT model_table_mark_id_fkey FOREIGN KEY (mark_id) REFERENCES public.mark_table(mark_id) ON DELETE CASCADE;
+
+
+--
+-- Name: car_table_plate_region_idx; Type: INDEX; Schema: public; Owner: user
+--
+
+CREATE INDEX car_table_plate_region_idx ON public.car_table USING btree (plate_region);
+
+
+--
+-- Name: mark_table_name_lower_uniq_idx; Type: INDEX; Schema: public; Owner: user
+--
+
+CREATE UNIQUE INDEX mark_table_name_lower_uniq_idx ON public.mark_table USING btree (lower((name)::text));
+
+
+--
+-- Name: model_table_name_lower_uniq_idx; Type: INDEX; Schema: public; Owner: user
+--
+
+CREATE UNIQUE INDEX model_table_name_lower_uniq_idx ON public.model_table USING btree (mark_id, lower((name)::text));
+
+
+--
+-- Name: idempotency_table_created_at_idx; Type: INDEX; Schema: public; Owner: user
+--
+
+CREATE INDEX idempotency_table_created_at_idx ON public.idempotency_table USING btree (created_at);
+
+
+--
+-- Name: car_suggest_view_uniq_idx; Type: INDEX; Schema: public; Owner: user
+--
+
+CREATE UNIQUE INDEX car_suggest_view_uniq_idx ON public.car_suggest_view USING btree (field, mark, value);
+
+
+--
+-- Name: car_suggest_view_prefix_idx; Type: INDEX; Schema: public; Owner: user
+--
+
+CREATE INDEX car_suggest_view_prefix_idx ON public.car_suggest_view USING btree (field, lower((value)::text) text_pattern_ops);
+
+
+--
+-- Name: car_suggest_view; Type: MATERIALIZED VIEW DATA; Schema: public; Owner: user
+--
+
+REFRESH MATERIALIZED VIEW public.car_suggest_view;
+
+
 --
 -- PostgreSQL database dump complete
 --
\ No newline at end of file